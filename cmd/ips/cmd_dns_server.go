@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(dnsServerCmd)
+
+	// dns server
+	dnsServerCmd.Flags().StringVarP(&dnsAddr, "addr", "a", "", UsageDNSAddr)
+	dnsServerCmd.Flags().StringVarP(&dnsZone, "zone", "z", "", UsageDNSZone)
+	dnsServerCmd.Flags().IntVarP(&dnsTTL, "ttl", "", 0, UsageDNSTTL)
+
+	// operate
+	dnsServerCmd.Flags().StringVarP(&fields, "fields", "f", "", UsageFields)
+	dnsServerCmd.Flags().BoolVarP(&useDBFields, "use-db-fields", "", false, UsageUseDBFields)
+	dnsServerCmd.Flags().StringVarP(&rewriteFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	dnsServerCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// database
+	dnsServerCmd.Flags().StringSliceVarP(&rootFile, "file", "i", nil, UsageQueryFile)
+	dnsServerCmd.Flags().StringSliceVarP(&rootFormat, "format", "", nil, UsageQueryFormat)
+	dnsServerCmd.Flags().StringSliceVarP(&rootIPv4File, "ipv4-file", "", nil, UsageQueryIPv4File)
+	dnsServerCmd.Flags().StringSliceVarP(&rootIPv4Format, "ipv4-format", "", nil, UsageQueryIPv4Format)
+	dnsServerCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
+	dnsServerCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
+	dnsServerCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	dnsServerCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
+	dnsServerCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+}
+
+var dnsServerCmd = &cobra.Command{
+	Use:   "dns-server",
+	Short: "Start IPS DNS server",
+	Long: `The 'ips dns-server' command starts a DNS server answering the geolocation of IP addresses via TXT records.
+
+Query names are IP addresses under the zone, in plain or reversed forms, e.g. with the zone geo.local:
+  8.8.8.8.geo.local, 8.8.4.4.in-addr.geo.local, 2001-db8--1.geo.local, <32 nibbles>.ip6.geo.local
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/dns_server.md`,
+	Example: `  # Start the DNS server on port 5353
+  ips dns-server -a :5353 --zone geo.local
+
+  # Query the geolocation of 8.8.8.8
+  dig @127.0.0.1 -p 5353 +short TXT 8.8.8.8.geo.local`,
+	PreRun: PreRunInit,
+	Run:    DNSServer,
+}
+
+func DNSServer(cmd *cobra.Command, args []string) {
+	if err := manager.DNSService(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -19,15 +19,15 @@ package ips
 import (
 	"fmt"
 	"log"
-	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
-
-	"github.com/sjzar/ips/internal/ips"
 )
 
 func init() {
 	rootCmd.AddCommand(downloadCmd)
+
+	downloadCmd.Flags().BoolVarP(&downloadList, "list", "l", false, UsageDownloadList)
 }
 
 var downloadCmd = &cobra.Command{
@@ -37,7 +37,10 @@ var downloadCmd = &cobra.Command{
 
 For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/download.md
 `,
-	Example: `  # To download a predefined database file
+	Example: `  # To list all known database files and their download sources
+  ips download --list
+
+  # To download a predefined database file
   ips download city.free.ipdb
 
   # To download a database file from a custom URL
@@ -51,17 +54,13 @@ For more detailed information and advanced configuration options, please refer t
 
 func Download(cmd *cobra.Command, args []string) {
 
+	if downloadList {
+		ShowDownloadSources()
+		return
+	}
+
 	if len(args) == 0 {
-		fmt.Printf("IPS Download List =============================================================\n")
-		list := make([]string, 0, len(ips.DownloadMap))
-		for k := range ips.DownloadMap {
-			list = append(list, k)
-		}
-		sort.Strings(list)
-		for _, k := range list {
-			fmt.Printf("%s: [%s]\n", k, ips.DownloadMap[k])
-		}
-		fmt.Println("===============================================================================")
+		ShowDownloadSources()
 		_ = cmd.Help()
 		return
 	}
@@ -77,3 +76,12 @@ func Download(cmd *cobra.Command, args []string) {
 		log.Printf("Download %s failed: %s", args[0], err)
 	}
 }
+
+// ShowDownloadSources prints all known database files and their download URLs, including mirrors.
+func ShowDownloadSources() {
+	fmt.Printf("IPS Download List =============================================================\n")
+	for _, source := range manager.DownloadSources() {
+		fmt.Printf("%s: [%s]\n", source.Name, strings.Join(source.URLs, ", "))
+	}
+	fmt.Println("===============================================================================")
+}
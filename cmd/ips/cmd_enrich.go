@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sjzar/ips/internal/ips"
+)
+
+func init() {
+	rootCmd.AddCommand(enrichCmd)
+
+	// enrich
+	enrichCmd.Flags().StringVarP(&enrichInputFile, "input-file", "i", "", UsageEnrichInputFile)
+	enrichCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageEnrichOutputFile)
+	enrichCmd.Flags().IntVarP(&enrichWorkers, "workers", "w", 0, UsageEnrichWorkers)
+	enrichCmd.Flags().IntVarP(&enrichColumn, "column", "c", 0, UsageEnrichColumn)
+	enrichCmd.Flags().StringVarP(&enrichDelimiter, "delimiter", "d", "", UsageEnrichDelimiter)
+	enrichCmd.Flags().StringVarP(&enrichRegexp, "regexp", "e", "", UsageEnrichRegexp)
+	enrichCmd.Flags().StringVarP(&enrichSeparator, "separator", "s", "", UsageEnrichSeparator)
+
+	// operate
+	enrichCmd.Flags().StringVarP(&fields, "fields", "f", "", UsageFields)
+	enrichCmd.Flags().StringVarP(&rewriteFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	enrichCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// database
+	enrichCmd.Flags().StringSliceVarP(&rootFile, "file", "", nil, UsageQueryFile)
+	enrichCmd.Flags().StringSliceVarP(&rootFormat, "format", "", nil, UsageQueryFormat)
+	enrichCmd.Flags().StringSliceVarP(&rootIPv4File, "ipv4-file", "", nil, UsageQueryIPv4File)
+	enrichCmd.Flags().StringSliceVarP(&rootIPv4Format, "ipv4-format", "", nil, UsageQueryIPv4Format)
+	enrichCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
+	enrichCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
+	enrichCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	enrichCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
+	enrichCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+}
+
+var enrichCmd = &cobra.Command{
+	Use:   "enrich [-i inputFile] [-o outputFile]",
+	Short: "Append IP geolocation fields to the lines of a file",
+	Long: `The 'ips enrich' command looks up the IP of each line of a file, such as an access log, and appends the selected fields as new columns.
+
+The lines are processed by parallel workers, and written in the same order as the input.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/enrich.md
+`,
+	Example: `  # Append the country and city of the first column of an nginx access log
+  ips enrich -i access.log -o access.geo.log -c 1 -f country,city
+
+  # Extract the IP by a regular expression, with 8 workers
+  ips enrich -i app.log -e 'client=(?P<ip>\S+)' -w 8
+
+  # Enrich a CSV file from the standard input
+  cat users.csv | ips enrich -c 3 -d , -s ,`,
+	PreRun: PreRunInit,
+	Run:    Enrich,
+}
+
+func Enrich(cmd *cobra.Command, args []string) {
+	option := ips.EnrichOption{
+		Workers:   enrichWorkers,
+		Column:    enrichColumn,
+		Delimiter: enrichDelimiter,
+		Regexp:    enrichRegexp,
+		Separator: enrichSeparator,
+	}
+	if err := manager.EnrichFile(enrichInputFile, outputFile, option); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -49,6 +49,7 @@ func init() {
 	// output
 	myipCmd.Flags().StringVarP(&rootTextFormat, "text-format", "", "", UsageTextFormat)
 	myipCmd.Flags().StringVarP(&rootTextValuesSep, "text-values-sep", "", "", UsageTextValuesSep)
+	myipCmd.Flags().StringVarP(&rootTemplate, "template", "t", "", UsageTemplate)
 	myipCmd.Flags().BoolVarP(&rootJson, "json", "j", false, UsageJson)
 	myipCmd.Flags().BoolVarP(&rootJsonIndent, "json-indent", "", false, UsageJsonIndent)
 	myipCmd.Flags().BoolVarP(&rootAlfred, "alfred", "", false, UsageAlfred)
@@ -49,7 +49,10 @@ var packCmd = &cobra.Command{
 For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/pack.md
 `,
 	Example: `  # Package IP Database and Specify Fields
-  ips pack -i geoip.mmdb -o geoip_custom.ipdb --fields "country,city"`,
+  ips pack -i geoip.mmdb -o geoip_custom.ipdb --fields "country,city"
+
+  # Export nftables sets of the networks grouped by country
+  ips pack -i geoip.mmdb -o geoip.nft --output-format nftables --output-option "group_by=country"`,
 	PreRun: PreRunInit,
 	Run:    Pack,
 }
@@ -53,14 +53,20 @@ func init() {
 	rootCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
 	rootCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	rootCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	rootCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
 	rootCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
 
 	// output
 	rootCmd.Flags().StringVarP(&rootTextFormat, "text-format", "", "", UsageTextFormat)
 	rootCmd.Flags().StringVarP(&rootTextValuesSep, "text-values-sep", "", "", UsageTextValuesSep)
+	rootCmd.Flags().StringVarP(&rootTemplate, "template", "t", "", UsageTemplate)
 	rootCmd.Flags().BoolVarP(&rootJson, "json", "j", false, UsageJson)
 	rootCmd.Flags().BoolVarP(&rootJsonIndent, "json-indent", "", false, UsageJsonIndent)
 	rootCmd.Flags().BoolVarP(&rootAlfred, "alfred", "", false, UsageAlfred)
+	rootCmd.Flags().StringVarP(&rootOutput, "output", "o", "", UsageOutput)
+	rootCmd.Flags().BoolVarP(&rootStats, "stats", "", false, UsageStats)
+	rootCmd.Flags().StringVarP(&rootStatsBy, "stats-by", "", "", UsageStatsBy)
+	rootCmd.Flags().IntVarP(&rootStatsTop, "stats-top", "", 0, UsageStatsTop)
 }
 
 var rootCmd = &cobra.Command{
@@ -81,7 +87,13 @@ For more detailed information and advanced configuration options, please refer t
   ips 8.8.8.8 -f "country,city" --text-format "%values" --text-values-sep ":"
 
   # Pipeline query
-  echo 8.8.8.8 | ips`,
+  echo 8.8.8.8 | ips
+
+  # Pipeline query with CSV output
+  cat ips.txt | ips --output csv
+
+  # Top 10 countries and ISPs of an access log
+  cat access.log | ips --stats --stats-by country,isp --stats-top 10`,
 	Args: cobra.MinimumNArgs(0),
 	CompletionOptions: cobra.CompletionOptions{
 		HiddenDefaultCmd: true,
@@ -100,6 +112,16 @@ func Execute() {
 // Root is the main logic for the IP query command. It also supports pipeline queries.
 func Root(cmd *cobra.Command, args []string) {
 
+	if rootStats {
+		option := ips.StatsOption{
+			GroupBy: rootStatsBy,
+			Top:     rootStatsTop,
+		}
+		if err := manager.EnableStats(option); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Check for pipeline mode
 	if len(args) == 0 {
 		if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeNamedPipe == 0 {
@@ -123,6 +145,8 @@ func Root(cmd *cobra.Command, args []string) {
 			}
 			fmt.Print(ret)
 		}
+		fmt.Print(manager.FlushOutput())
+		printStats()
 		return
 	}
 
@@ -130,10 +154,32 @@ func Root(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if rootStats {
+		printStats()
+		return
+	}
 
+	// the table, CSV and TSV outputs end with a newline already
+	ret += manager.FlushOutput()
+	if strings.HasSuffix(ret, "\n") {
+		fmt.Print(ret)
+		return
+	}
 	fmt.Println(ret)
 }
 
+// printStats prints the statistics of the results, if enabled.
+func printStats() {
+	if !rootStats {
+		return
+	}
+	ret, err := manager.FlushStats()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(ret)
+}
+
 // PreRunInit is called before the main Run function. It sets up logging and initializes the IP manager.
 func PreRunInit(cmd *cobra.Command, args []string) {
 	log.SetFormatter(&log.TextFormatter{
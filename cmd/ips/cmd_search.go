@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	// search
+	searchCmd.Flags().StringArrayVarP(&searchWhere, "where", "w", nil, UsageSearchWhere)
+	searchCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", UsageSearchOutputFile)
+	searchCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
+
+	// operate
+	searchCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	searchCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// database
+	searchCmd.Flags().StringSliceVarP(&rootFile, "file", "i", nil, UsageQueryFile)
+	searchCmd.Flags().StringSliceVarP(&rootFormat, "format", "", nil, UsageQueryFormat)
+	searchCmd.Flags().StringSliceVarP(&rootIPv4File, "ipv4-file", "", nil, UsageQueryIPv4File)
+	searchCmd.Flags().StringSliceVarP(&rootIPv4Format, "ipv4-format", "", nil, UsageQueryIPv4Format)
+	searchCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
+	searchCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
+	searchCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	searchCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search -w condition [-o outputFile]",
+	Short: "List the networks matching field conditions",
+	Long: `The 'ips search' command walks the IPv4 and IPv6 databases, and outputs the minimal CIDR list of the networks matching the conditions, such as for firewall or routing lists.
+
+The conditions use the syntax of the field selector rules, "!" negates and "/" separates alternatives.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/search.md
+`,
+	Example: `  # All networks of China Mobile in Guangdong
+  ips search -w 'country=中国&province=广东&isp=移动'
+
+  # Networks of China except Guangdong and Guangxi, from a specific database
+  ips search -i GeoLite2-City.mmdb --lang en -w 'country=China&province=!Guangdong/Guangxi' -o cn.txt
+
+  # Multiple lists at once
+  ips search -w 'isp=电信' -w 'isp=联通'`,
+	PreRun: PreRunInit,
+	Run:    Search,
+}
+
+func Search(cmd *cobra.Command, args []string) {
+	if len(searchWhere) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	if err := manager.SearchFile(searchWhere, outputFile); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -17,13 +17,20 @@
 package ips
 
 import (
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	// server
-	serverCmd.Flags().StringVarP(&addr, "addr", "a", "", "Listen address")
+	serverCmd.Flags().StringVarP(&addr, "addr", "a", "", UsageServerAddr)
+	serverCmd.Flags().StringVarP(&tlsCert, "tls-cert", "", "", UsageTLSCert)
+	serverCmd.Flags().StringVarP(&tlsKey, "tls-key", "", "", UsageTLSKey)
+	serverCmd.Flags().StringVarP(&grpcAddr, "grpc-addr", "", "", UsageGRPCAddr)
+	serverCmd.Flags().BoolVarP(&metrics, "metrics", "", false, UsageMetrics)
+	serverCmd.Flags().StringSliceVarP(&trustedProxies, "trusted-proxies", "", nil, UsageTrustedProxies)
+	serverCmd.Flags().BoolVarP(&proxyProtocol, "proxy-protocol", "", false, UsageProxyProtocol)
 
 	// operate
 	serverCmd.Flags().StringVarP(&fields, "fields", "f", "", UsageFields)
@@ -39,6 +46,7 @@ func init() {
 	serverCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
 	serverCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
 	serverCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	serverCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
 	serverCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
 
 }
@@ -51,5 +59,7 @@ var serverCmd = &cobra.Command{
 }
 
 func Server(cmd *cobra.Command, args []string) {
-	manager.Service()
+	if err := manager.Service(); err != nil {
+		log.Fatal(err)
+	}
 }
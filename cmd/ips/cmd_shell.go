@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+
+	// operate
+	shellCmd.Flags().StringVarP(&fields, "fields", "f", "", UsageFields)
+	shellCmd.Flags().BoolVarP(&useDBFields, "use-db-fields", "", false, UsageUseDBFields)
+	shellCmd.Flags().StringVarP(&rewriteFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	shellCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// database
+	shellCmd.Flags().StringSliceVarP(&rootFile, "file", "i", nil, UsageQueryFile)
+	shellCmd.Flags().StringSliceVarP(&rootFormat, "format", "", nil, UsageQueryFormat)
+	shellCmd.Flags().StringSliceVarP(&rootIPv4File, "ipv4-file", "", nil, UsageQueryIPv4File)
+	shellCmd.Flags().StringSliceVarP(&rootIPv4Format, "ipv4-format", "", nil, UsageQueryIPv4Format)
+	shellCmd.Flags().StringSliceVarP(&rootIPv6File, "ipv6-file", "", nil, UsageQueryIPv6File)
+	shellCmd.Flags().StringSliceVarP(&rootIPv6Format, "ipv6-format", "", nil, UsageQueryIPv6Format)
+	shellCmd.Flags().StringVarP(&readerOption, "database-option", "", "", UsageReaderOption)
+	shellCmd.Flags().IntVarP(&cacheSize, "cache-size", "", 0, UsageCacheSize)
+	shellCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+
+	// output
+	shellCmd.Flags().StringVarP(&rootTextFormat, "text-format", "", "", UsageTextFormat)
+	shellCmd.Flags().StringVarP(&rootTextValuesSep, "text-values-sep", "", "", UsageTextValuesSep)
+	shellCmd.Flags().StringVarP(&rootTemplate, "template", "t", "", UsageTemplate)
+	shellCmd.Flags().BoolVarP(&rootJsonIndent, "json-indent", "", false, UsageJsonIndent)
+	shellCmd.Flags().StringVarP(&rootOutput, "output", "o", "", UsageOutput)
+}
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Interactive prompt of queries",
+	Long: `The 'ips shell' command starts an interactive prompt, which keeps the databases loaded across the queries.
+
+Type IPs, domains or any text to query, or paste a block of text such as log lines. The commands start with ":", e.g. ":set output table" changes the output type, and ":info" shows the meta of the loaded databases. Type ":help" for all the commands.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/shell.md
+`,
+	Example: `  # Start the shell with the default databases
+  ips shell
+
+  # Start the shell with a specific database and fields
+  ips shell -i GeoLite2-City.mmdb -f country,city --lang en`,
+	Args:   cobra.NoArgs,
+	PreRun: PreRunInit,
+	Run:    Shell,
+}
+
+func Shell(cmd *cobra.Command, args []string) {
+	if err := manager.NewShell(os.Stdout).Run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sjzar/ips/internal/ips"
+)
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+
+	// split
+	splitCmd.Flags().StringVarP(&splitBy, "by", "b", "", UsageSplitBy)
+	splitCmd.Flags().StringVarP(&splitNameMap, "name-map", "m", "", UsageSplitNameMap)
+	splitCmd.Flags().StringVarP(&splitWhere, "where", "w", "", UsageSplitWhere)
+
+	// operate
+	splitCmd.Flags().StringVarP(&dpFields, "fields", "f", "", UsageDPFields)
+	splitCmd.Flags().StringVarP(&dpRewriterFiles, "rewrite-files", "r", "", UsageRewriteFiles)
+	splitCmd.Flags().StringVarP(&lang, "lang", "", "", UsageLang)
+
+	// input & output
+	splitCmd.Flags().StringSliceVarP(&inputFile, "input-file", "i", nil, UsageDPInputFile)
+	splitCmd.Flags().StringSliceVarP(&inputFormat, "input-format", "", nil, UsageDPInputFormat)
+	splitCmd.Flags().StringVarP(&readerOption, "input-option", "", "", UsageReaderOption)
+	splitCmd.Flags().StringVarP(&hybridMode, "hybrid-mode", "", "aggregation", UsageHybridMode)
+	splitCmd.Flags().StringVarP(&splitOutputDir, "output-dir", "o", "", UsageSplitOutputDir)
+	splitCmd.Flags().StringVarP(&outputFormat, "output-format", "", "", UsageSplitOutputFmt)
+	splitCmd.Flags().StringVarP(&writerOption, "output-option", "", "", UsageWriterOption)
+	splitCmd.Flags().IntVarP(&readerJobs, "reader-jobs", "", 0, UsageReaderJobs)
+}
+
+var splitCmd = &cobra.Command{
+	Use:   "split -i inputFile --by field -o outputDir [--output-format format]",
+	Short: "Split IP database file into files by field value",
+	Long: `The 'ips split' command walks an IP database once, and writes the networks of each value of a field, such as each country, into its own file of the output directory.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/split.md
+`,
+	Example: `  # One plain text file per country
+  ips split -i GeoLite2-City.mmdb --by country -o countries/
+
+  # One ipdb file per province of China, named by a mapping file
+  ips split -i qqwry.dat --by province -w 'country=中国' -m provinces.txt -o provinces/ --output-format ipdb`,
+	PreRun: PreRunInit,
+	Run:    Split,
+}
+
+func Split(cmd *cobra.Command, args []string) {
+	if len(inputFile) == 0 || len(splitBy) == 0 || len(splitOutputDir) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	option := ips.SplitOption{
+		By:           splitBy,
+		OutputDir:    splitOutputDir,
+		OutputFormat: outputFormat,
+		NameMap:      splitNameMap,
+		Where:        splitWhere,
+	}
+	if err := manager.Split(inputFormat, inputFile, option); err != nil {
+		log.Fatal(err)
+	}
+}
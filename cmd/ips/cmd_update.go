@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().BoolVarP(&updateForce, "force", "", false, UsageUpdateForce)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update [database_name...]",
+	Short: "Update downloaded IP database files",
+	Long: `The 'ips update' command checks the downloaded IP database files for new versions and downloads the changed ones.
+
+The version of each downloaded file is recorded in the manifest.json file of the IPS working directory, unchanged files are skipped by conditional requests.
+
+For more detailed information and advanced configuration options, please refer to https://github.com/sjzar/ips/blob/main/docs/download.md
+`,
+	Example: `  # Update all downloaded database files
+  ips update
+
+  # Update the specified database files
+  ips update qqwry.dat dbip-city-lite.mmdb
+
+  # Download the database files again even if they are unchanged
+  ips update --force`,
+	PreRun: PreRunInit,
+	Run:    Update,
+}
+
+func Update(cmd *cobra.Command, args []string) {
+	if err := manager.Update(args, updateForce); err != nil {
+		log.Fatal(err)
+	}
+}
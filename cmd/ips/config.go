@@ -82,6 +82,9 @@ var (
 	// rootTextValuesSep defines the separator for text output.
 	rootTextValuesSep string
 
+	// rootTemplate defines the text template for text output.
+	rootTemplate string
+
 	// rootJson defines whether to output in JSON format.
 	rootJson bool
 
@@ -91,6 +94,18 @@ var (
 	// rootAlfred defines whether to output in Alfred format.
 	rootAlfred bool
 
+	// rootOutput defines the type of the output.
+	rootOutput string
+
+	// rootStats defines whether to output the statistics of the results instead.
+	rootStats bool
+
+	// rootStatsBy defines the groups of the statistics.
+	rootStatsBy string
+
+	// rootStatsTop defines the number of the most frequent values in each group of the statistics.
+	rootStatsTop int
+
 	// dump & pack command flags
 	// operate
 	// dpFields specifies the fields to output for dump and pack operations.
@@ -121,6 +136,17 @@ var (
 	// readerJobs specifies the number of concurrent reader jobs.
 	readerJobs int
 
+	// cacheSize specifies the number of IP ranges cached by each reader.
+	cacheSize int
+
+	// download
+	// downloadList indicates whether to list all known download sources.
+	downloadList bool
+
+	// update
+	// updateForce indicates whether to download the database files even if they are unchanged.
+	updateForce bool
+
 	// myip
 	// localAddr specifies the local address (in IP format) that should be used for outbound connections.
 	// Useful in systems with multiple network interfaces.
@@ -138,6 +164,74 @@ var (
 	// addr specifies the server address.
 	addr string
 
+	// tlsCert specifies the certificate file to serve HTTPS.
+	tlsCert string
+
+	// tlsKey specifies the key file to serve HTTPS.
+	tlsKey string
+
+	// grpcAddr specifies the gRPC service address.
+	grpcAddr string
+
+	// metrics indicates whether to expose Prometheus metrics.
+	metrics bool
+
+	// trustedProxies specifies the IPs or CIDRs of the trusted proxies.
+	trustedProxies []string
+
+	// proxyProtocol indicates whether to accept PROXY protocol headers.
+	proxyProtocol bool
+
+	// dns server
+
+	// dnsAddr specifies the DNS server address.
+	dnsAddr string
+
+	// dnsZone specifies the zone answered by the DNS server.
+	dnsZone string
+
+	// dnsTTL specifies the TTL in seconds of the DNS answers.
+	dnsTTL int
+
+	// enrich
+
+	// enrichInputFile specifies the input file of the lines to enrich.
+	enrichInputFile string
+
+	// enrichWorkers specifies the number of concurrent enrich workers.
+	enrichWorkers int
+
+	// enrichColumn specifies the 1-based column of the IP.
+	enrichColumn int
+
+	// enrichDelimiter specifies the delimiter of the columns.
+	enrichDelimiter string
+
+	// enrichRegexp specifies the pattern extracting the IP.
+	enrichRegexp string
+
+	// enrichSeparator specifies the separator of the appended fields.
+	enrichSeparator string
+
+	// search
+
+	// searchWhere specifies the conditions of the networks to search.
+	searchWhere []string
+
+	// split
+
+	// splitBy specifies the field splitting the networks.
+	splitBy string
+
+	// splitOutputDir specifies the directory of the split files.
+	splitOutputDir string
+
+	// splitNameMap specifies the file mapping the values to the file names.
+	splitNameMap string
+
+	// splitWhere specifies the condition of the networks to split.
+	splitWhere string
+
 	// mdns
 
 	// dnsClientNet specifies the network protocol to be used by the DNS client. tcp, udp, tcp-tls.
@@ -231,6 +325,10 @@ func GetFlagConfig() *ips.Config {
 		conf.TextValuesSep = rootTextValuesSep
 	}
 
+	if len(rootTemplate) != 0 {
+		conf.TextTemplate = rootTemplate
+	}
+
 	if rootJson {
 		conf.OutputType = ips.OutputTypeJSON
 	}
@@ -244,6 +342,10 @@ func GetFlagConfig() *ips.Config {
 		conf.OutputType = ips.OutputTypeAlfred
 	}
 
+	if len(rootOutput) != 0 {
+		conf.OutputType = rootOutput
+	}
+
 	// dump & pack command flags
 	if len(dpFields) != 0 {
 		conf.DPFields = dpFields
@@ -265,10 +367,50 @@ func GetFlagConfig() *ips.Config {
 		conf.ReaderJobs = readerJobs
 	}
 
+	if cacheSize != 0 {
+		conf.CacheSize = cacheSize
+	}
+
 	if len(addr) != 0 {
 		conf.Addr = addr
 	}
 
+	if len(tlsCert) != 0 {
+		conf.TLSCert = tlsCert
+	}
+
+	if len(tlsKey) != 0 {
+		conf.TLSKey = tlsKey
+	}
+
+	if len(dnsAddr) != 0 {
+		conf.DNSAddr = dnsAddr
+	}
+
+	if len(dnsZone) != 0 {
+		conf.DNSZone = dnsZone
+	}
+
+	if dnsTTL > 0 {
+		conf.DNSTTL = dnsTTL
+	}
+
+	if len(grpcAddr) != 0 {
+		conf.GRPCAddr = grpcAddr
+	}
+
+	if metrics {
+		conf.Metrics = metrics
+	}
+
+	if len(trustedProxies) != 0 {
+		conf.TrustedProxies = trustedProxies
+	}
+
+	if proxyProtocol {
+		conf.ProxyProtocol = proxyProtocol
+	}
+
 	if len(localAddr) != 0 {
 		conf.LocalAddr = localAddr
 	}
@@ -47,12 +47,44 @@ const (
 	UsageWriterOption     = "Additional options for the database writer, if applicable."
 	UsageHybridMode       = "Sets mode for multi-IP source handling; 'comparison' to compare, 'aggregation' to merge data."
 	UsageReaderJobs       = "Set the number of concurrent reader jobs. This parameter controls the parallelism level of reading operations."
+	UsageCacheSize        = "Set the number of IP ranges cached by each database reader. The cache is disabled if not positive."
+	UsageDownloadList     = "List all known database files and their download sources."
+	UsageEnrichInputFile  = "Path to the input file of the lines to enrich. Defaults to standard input if not specified."
+	UsageEnrichOutputFile = "Destination path for the enriched lines. Defaults to standard output if not specified."
+	UsageEnrichWorkers    = "Number of concurrent workers. (default the number of CPUs)"
+	UsageEnrichColumn     = "1-based column of the IP, split by the delimiter. Defaults to the first IP in the line."
+	UsageEnrichDelimiter  = "Delimiter of the columns. Defaults to white spaces."
+	UsageEnrichRegexp     = "Regular expression extracting the IP, by the \"ip\" named group, the first group or the whole match."
+	UsageEnrichSeparator  = "Separator of the appended fields. (default tab)"
+	UsageSearchWhere      = "Condition of the networks, e.g. \"country=中国&province=广东&isp=移动\". \"!\" negates and \"/\" separates alternatives. Repeat for multiple lists."
+	UsageSearchOutputFile = "Destination path for the CIDR list. Defaults to standard output if not specified."
+	UsageSplitBy          = "Field splitting the networks into files, e.g. \"country\" (required)."
+	UsageSplitOutputDir   = "Directory of the output files (required)."
+	UsageSplitOutputFmt   = "The format of the output files. (default \"plain\")"
+	UsageSplitNameMap     = "File of \"value=name\" lines mapping the values to the file names. Other values are named by themselves."
+	UsageSplitWhere       = "Condition of the networks to split, e.g. \"country=中国\"."
+	UsageDNSAddr          = "Listen address of the DNS server. (default \":5353\")"
+	UsageDNSZone          = "Zone answered by the DNS server. (default \"geo.local\")"
+	UsageDNSTTL           = "TTL in seconds of the DNS answers. (default 60)"
+	UsageGRPCAddr         = "Listen address of the gRPC service, the gRPC service is disabled if empty."
+	UsageServerAddr       = "Listen addresses, separated by commas. A unix socket address starts with \"unix:\", e.g. \":6860,unix:/run/ips.sock\"."
+	UsageTLSCert          = "Certificate file to serve HTTPS, reloaded when changed."
+	UsageTLSKey           = "Key file to serve HTTPS, reloaded when changed."
+	UsageMetrics          = "Expose Prometheus metrics on /metrics."
+	UsageTrustedProxies   = "IPs or CIDRs of the trusted proxies, whose forwarding headers are used to resolve the client IP."
+	UsageProxyProtocol    = "Accept PROXY protocol v1/v2 headers on the listener."
+	UsageUpdateForce      = "Download the database files even if they have not changed."
 
 	// Output Flags
 
 	UsageTextFormat    = "Specify the desired format for text output. (default \"%origin [%values]\")"
 	UsageTextValuesSep = "Specify the separator for values in text output. (default \" \")"
+	UsageTemplate      = "Specify the Go text/template for each IP and domain in text output, e.g. \"{{.IP}} {{.country}}/{{.city}}\"."
 	UsageJson          = "Output the results in JSON format."
 	UsageJsonIndent    = "Output the results in indent JSON format."
 	UsageAlfred        = "Output the results in Alfred format."
+	UsageOutput        = "Output type of the results: text, json, alfred, csv, tsv, ndjson, yaml or table."
+	UsageStats         = "Output the statistics of the results instead, as a table, JSON or CSV."
+	UsageStatsBy       = "Groups of the statistics separated by commas, with the fields of a group combined by \"+\", e.g. \"country,country+province\". (default each field)"
+	UsageStatsTop      = "Number of the most frequent values in each group of the statistics, others are counted as one row. (default all)"
 )
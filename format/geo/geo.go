@@ -51,9 +51,17 @@ func init() {
 
 // SetLanguage sets the global language for geolocation information.
 func SetLanguage(lang string) error {
+	if err := CheckLanguage(lang); err != nil {
+		return err
+	}
+	Language = lang
+	return nil
+}
+
+// CheckLanguage checks whether the language is supported.
+func CheckLanguage(lang string) error {
 	for _, l := range SupportedLanguages {
 		if l == lang {
-			Language = lang
 			return nil
 		}
 	}
@@ -46,6 +46,18 @@ func Translate(field, text string) string {
 	return translate(DatabaseLanguage, Language, field, text)
 }
 
+// TranslateTo translates the provided text from the database language to the target language.
+// If the text cannot be translated, it returns the original text.
+func TranslateTo(targetLang, field, text string) string {
+	return translate(DatabaseLanguage, targetLang, field, text)
+}
+
+// TranslateFrom translates the provided text from the source language to the target language.
+// If the text cannot be translated, it returns the original text.
+func TranslateFrom(sourceLang, targetLang, field, text string) string {
+	return translate(sourceLang, targetLang, field, text)
+}
+
 // translate translates the provided text from the source language to the target language.
 // If the text cannot be translated, it returns the original text.
 func translate(sourceLang, targetLang, field, text string) string {
@@ -36,4 +36,8 @@ func TestTranslate(t *testing.T) {
 	ast.Equal("Shanghai", Translate("city", "上海"))
 	ast.Equal("Los Angeles", Translate("city", "洛杉矶"))
 	ast.Equal("England", Translate("province", "英格兰"))
+
+	ast.Equal("Chine", TranslateTo(LangFrench, "country", "中国"))
+	ast.Equal("中国", TranslateTo(LangChinese, "country", "中国"))
+	ast.Equal("China", Translate("country", "中国"))
 }
@@ -156,6 +156,13 @@ func (w *Writer) ConvertMap(fields, values []string) map[string]interface{} {
 	return ret
 }
 
+// ConvertMap converts fields and values to a map in the MMDB data structure with the options,
+// which is the same as the GeoIP2 databases and web services.
+func ConvertMap(fields, values []string, option WriterOption) map[string]interface{} {
+	w := &Writer{option: option}
+	return w.ConvertMap(fields, values)
+}
+
 // convertGeoInfo converts the given value to its corresponding geo information.
 func (w *Writer) convertGeoInfo(field, value string) interface{} {
 	info, ok := geo.GetInfoByName(field, value)
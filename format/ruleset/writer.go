@@ -0,0 +1,427 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ruleset
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// Formats of the rule sets, deployable artifacts of firewalls, web servers, DNS servers and proxies.
+const (
+	FormatIPSet    = "ipset"
+	FormatNftables = "nftables"
+	FormatNginx    = "nginx"
+	FormatHAProxy  = "haproxy"
+	FormatBind     = "bind"
+	FormatClash    = "clash"
+	FormatSurge    = "surge"
+)
+
+// DefaultName is the default name of the sets, the table and the variable.
+const DefaultName = "ips"
+
+// MaxSetNameLength is the maximum length of the names of the sets, the limit of ipset.
+// The identifiers of the groups are cut to fit the names in it.
+const MaxSetNameLength = 31
+
+// MinIdentifierLength is the minimum length left to the identifiers of the groups in the ipset names.
+const MinIdentifierLength = 8
+
+// Formats lists the formats of the rule sets.
+var Formats = []string{FormatIPSet, FormatNftables, FormatNginx, FormatHAProxy, FormatBind, FormatClash, FormatSurge}
+
+// Exts maps the formats of the rule sets to their file extensions.
+var Exts = map[string]string{
+	FormatIPSet:    ".ipset",
+	FormatNftables: ".nft",
+	FormatNginx:    ".conf",
+	FormatHAProxy:  ".map",
+	FormatBind:     ".acl",
+	FormatClash:    ".yaml",
+	FormatSurge:    ".list",
+}
+
+// Writer writes the networks grouped by the value of a field as a rule set.
+// The adjacent networks of a group are aggregated, and the networks without the value are skipped.
+type Writer struct {
+	format string
+	meta   *model.Meta
+	option WriterOption
+	groups map[string]ipnet.Ranges
+}
+
+// WriterOption provides options for the Writer.
+type WriterOption struct {
+	// GroupBy is the field grouping the networks. Defaults to the first field of the database.
+	GroupBy string
+
+	// Groups is the values of the groups to write. Defaults to all.
+	Groups []string
+
+	// Name is the name of the sets, the table and the variable. Defaults to DefaultName.
+	Name string
+
+	// Policy is the policy of the Clash and Surge rules. Defaults to the value of the group.
+	Policy string
+}
+
+// NewWriter initializes a new Writer of the rule set format.
+func NewWriter(format string, meta *model.Meta) (*Writer, error) {
+	supported := false
+	for _, f := range Formats {
+		if f == format {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, errors.ErrUnsupportedFormat
+	}
+	if meta == nil {
+		return nil, errors.ErrMetaMissing
+	}
+
+	w := &Writer{
+		format: format,
+		meta:   meta,
+		groups: make(map[string]ipnet.Ranges),
+	}
+	if err := w.SetOption(WriterOption{}); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetOption sets the provided options to the Writer.
+func (w *Writer) SetOption(option interface{}) error {
+	opt, ok := option.(WriterOption)
+	if !ok {
+		return nil
+	}
+	if len(opt.GroupBy) == 0 && len(w.meta.Fields) != 0 {
+		opt.GroupBy = w.meta.Fields[0]
+	}
+	if !w.meta.SupportFields()[opt.GroupBy] {
+		return errors.ErrFieldInvalid
+	}
+	if len(opt.Name) == 0 {
+		opt.Name = DefaultName
+	}
+	if w.format == FormatIPSet && len(opt.Name) > MaxSetNameLength-len("__v6")-MinIdentifierLength {
+		return errors.ErrNameTooLong
+	}
+	w.option = opt
+	return nil
+}
+
+// Insert adds the network of the IP information to its group.
+func (w *Writer) Insert(info *model.IPInfo) error {
+	value, _ := info.GetData(w.option.GroupBy)
+	if len(value) == 0 || info.IPNet == nil {
+		return nil
+	}
+	if len(w.option.Groups) != 0 {
+		selected := false
+		for _, group := range w.option.Groups {
+			if group == value {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			return nil
+		}
+	}
+	w.groups[value] = append(w.groups[value], ipnet.Range{
+		Start: info.IPNet.Start.To16(),
+		End:   info.IPNet.End.To16(),
+	})
+	return nil
+}
+
+// WriteTo writes the rule set into the provided writer.
+func (w *Writer) WriteTo(writer io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# Generated by ips, grouped by %s\n", w.option.GroupBy)
+
+	groups := w.sortedGroups()
+	switch w.format {
+	case FormatIPSet:
+		w.writeIPSet(buf, groups)
+	case FormatNftables:
+		w.writeNftables(buf, groups)
+	case FormatNginx:
+		w.writeNginx(buf, groups)
+	case FormatHAProxy:
+		w.writeHAProxy(buf, groups)
+	case FormatBind:
+		w.writeBind(buf, groups)
+	case FormatClash:
+		w.writeClash(buf, groups)
+	case FormatSurge:
+		w.writeSurge(buf, groups)
+	}
+
+	return buf.WriteTo(writer)
+}
+
+// WriterFormat returns the format of the writer.
+func (w *Writer) WriterFormat() string {
+	return w.format
+}
+
+// group is the aggregated networks of a value.
+type group struct {
+	value string
+	ident string
+	ipv4  []*net.IPNet
+	ipv6  []*net.IPNet
+}
+
+// sortedGroups returns the groups sorted by the value, with their aggregated networks split by the IP version.
+func (w *Writer) sortedGroups() []*group {
+	values := make([]string, 0, len(w.groups))
+	for value := range w.groups {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	// the ipset names are made of the name, the identifier and the suffix of the IP version
+	maxLength := MaxSetNameLength - len("_v6")
+	if w.format == FormatIPSet {
+		maxLength -= len(w.option.Name) + len("_")
+	}
+	idents := identifiers(values, maxLength)
+
+	ret := make([]*group, 0, len(values))
+	for i, value := range values {
+		g := &group{value: value, ident: idents[i]}
+		for _, ipNet := range w.groups[value].IPNets() {
+			if ipNet.IP.To4() != nil {
+				g.ipv4 = append(g.ipv4, ipNet)
+			} else {
+				g.ipv6 = append(g.ipv6, ipNet)
+			}
+		}
+		ret = append(ret, g)
+	}
+	return ret
+}
+
+// writeIPSet writes an "ipset restore" script, a hash:net set of each group and IP version.
+func (w *Writer) writeIPSet(buf *bytes.Buffer, groups []*group) {
+	for _, g := range groups {
+		fmt.Fprintf(buf, "# %s\n", g.value)
+		for _, set := range []struct {
+			name   string
+			family string
+			ipNets []*net.IPNet
+		}{
+			{w.option.Name + "_" + g.ident, "inet", g.ipv4},
+			{w.option.Name + "_" + g.ident + "_v6", "inet6", g.ipv6},
+		} {
+			if len(set.ipNets) == 0 {
+				continue
+			}
+			maxElem := 65536
+			if len(set.ipNets) > maxElem {
+				maxElem = len(set.ipNets)
+			}
+			fmt.Fprintf(buf, "create %s hash:net family %s maxelem %d -exist\n", set.name, set.family, maxElem)
+			fmt.Fprintf(buf, "flush %s\n", set.name)
+			for _, ipNet := range set.ipNets {
+				fmt.Fprintf(buf, "add %s %s\n", set.name, ipNet)
+			}
+		}
+	}
+}
+
+// writeNftables writes an nftables table, an interval set of each group and IP version.
+func (w *Writer) writeNftables(buf *bytes.Buffer, groups []*group) {
+	fmt.Fprintf(buf, "table inet %s {\n", w.option.Name)
+	for _, g := range groups {
+		for _, set := range []struct {
+			name   string
+			typ    string
+			ipNets []*net.IPNet
+		}{
+			{g.ident + "_v4", "ipv4_addr", g.ipv4},
+			{g.ident + "_v6", "ipv6_addr", g.ipv6},
+		} {
+			if len(set.ipNets) == 0 {
+				continue
+			}
+			fmt.Fprintf(buf, "\t# %s\n", g.value)
+			fmt.Fprintf(buf, "\tset %s {\n\t\ttype %s\n\t\tflags interval\n\t\telements = {\n", set.name, set.typ)
+			for i, ipNet := range set.ipNets {
+				sep := ","
+				if i == len(set.ipNets)-1 {
+					sep = ""
+				}
+				fmt.Fprintf(buf, "\t\t\t%s%s\n", ipNet, sep)
+			}
+			buf.WriteString("\t\t}\n\t}\n")
+		}
+	}
+	buf.WriteString("}\n")
+}
+
+// writeNginx writes a block of the nginx geo module, setting the variable to the value of the group.
+func (w *Writer) writeNginx(buf *bytes.Buffer, groups []*group) {
+	fmt.Fprintf(buf, "geo $%s {\n\tdefault \"\";\n", w.option.Name)
+	for _, g := range groups {
+		value := quote(g.value)
+		for _, ipNet := range append(g.ipv4, g.ipv6...) {
+			fmt.Fprintf(buf, "\t%s %s;\n", ipNet, value)
+		}
+	}
+	buf.WriteString("}\n")
+}
+
+// writeHAProxy writes an HAProxy map file, used by the map_ip converter.
+func (w *Writer) writeHAProxy(buf *bytes.Buffer, groups []*group) {
+	for _, g := range groups {
+		for _, ipNet := range append(g.ipv4, g.ipv6...) {
+			fmt.Fprintf(buf, "%s %s\n", ipNet, g.value)
+		}
+	}
+}
+
+// writeBind writes a Bind ACL of each group.
+func (w *Writer) writeBind(buf *bytes.Buffer, groups []*group) {
+	for _, g := range groups {
+		fmt.Fprintf(buf, "acl %s {\n", quote(w.option.Name+"_"+g.value))
+		for _, ipNet := range append(g.ipv4, g.ipv6...) {
+			fmt.Fprintf(buf, "\t%s;\n", ipNet)
+		}
+		buf.WriteString("};\n")
+	}
+}
+
+// writeClash writes the Clash rules of the groups.
+func (w *Writer) writeClash(buf *bytes.Buffer, groups []*group) {
+	buf.WriteString("rules:\n")
+	for _, g := range groups {
+		for _, rule := range w.proxyRules(g) {
+			fmt.Fprintf(buf, "  - %s\n", rule)
+		}
+	}
+}
+
+// writeSurge writes the Surge rules of the groups.
+func (w *Writer) writeSurge(buf *bytes.Buffer, groups []*group) {
+	buf.WriteString("[Rule]\n")
+	for _, g := range groups {
+		for _, rule := range w.proxyRules(g) {
+			fmt.Fprintf(buf, "%s\n", rule)
+		}
+	}
+}
+
+// proxyRules returns the IP-CIDR rules of the group shared by Clash and Surge.
+func (w *Writer) proxyRules(g *group) []string {
+	policy := w.option.Policy
+	if len(policy) == 0 {
+		policy = g.value
+	}
+	ret := make([]string, 0, len(g.ipv4)+len(g.ipv6))
+	for _, ipNet := range g.ipv4 {
+		ret = append(ret, fmt.Sprintf("IP-CIDR,%s,%s,no-resolve", ipNet, policy))
+	}
+	for _, ipNet := range g.ipv6 {
+		ret = append(ret, fmt.Sprintf("IP-CIDR6,%s,%s,no-resolve", ipNet, policy))
+	}
+	return ret
+}
+
+// identifiers returns the identifiers of the sorted values, unique and at most maxLength long.
+// The values sharing an identifier, e.g. "AS1 电信" and "AS1 移动", are suffixed by their indexes.
+func identifiers(values []string, maxLength int) []string {
+	ret := make([]string, len(values))
+	count := make(map[string]int, len(values))
+	for i, value := range values {
+		ret[i] = truncate(identifier(value, i+1), maxLength)
+		count[ret[i]]++
+	}
+
+	used := make(map[string]bool, len(values))
+	for _, ident := range ret {
+		if count[ident] == 1 {
+			used[ident] = true
+		}
+	}
+	for i, ident := range ret {
+		if count[ident] == 1 {
+			continue
+		}
+		for n := i + 1; ; n += len(values) {
+			suffix := "_" + strconv.Itoa(n)
+			ret[i] = truncate(ident, maxLength-len(suffix)) + suffix
+			if !used[ret[i]] {
+				break
+			}
+		}
+		used[ret[i]] = true
+	}
+	return ret
+}
+
+// truncate returns the first length bytes of the ASCII identifier.
+func truncate(ident string, length int) string {
+	if length < 0 {
+		length = 0
+	}
+	if len(ident) > length {
+		return ident[:length]
+	}
+	return ident
+}
+
+// identifier returns the value as an identifier of sets, made of ASCII letters, digits and underscores.
+// Other characters are replaced by underscores, and a value without any letter or digit is named by its index.
+func identifier(value string, index int) string {
+	alnum := false
+	ret := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			alnum = true
+			return r
+		case r == '_':
+			return r
+		}
+		return '_'
+	}, value)
+	if !alnum {
+		return fmt.Sprintf("group%d", index)
+	}
+	return ret
+}
+
+// quote returns the value in double quotes.
+func quote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
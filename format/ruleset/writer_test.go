@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ruleset
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func newTestWriter(t *testing.T, format string, option WriterOption) *Writer {
+	meta := &model.Meta{
+		IPVersion: model.IPv4 | model.IPv6,
+		Fields:    []string{"country", "isp"},
+	}
+	writer, err := NewWriter(format, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.SetOption(option); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range []struct {
+		cidr    string
+		country string
+	}{
+		{"1.0.0.0/25", "CN"},
+		{"1.0.0.128/25", "CN"},
+		{"2.0.0.0/24", "US"},
+		{"3.0.0.0/24", ""},
+		{"2001:db8::/33", "CN"},
+		{"2001:db8:8000::/33", "CN"},
+	} {
+		_, ipNet, err := net.ParseCIDR(item.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Insert(&model.IPInfo{
+			IP:     ipNet.IP,
+			IPNet:  ipnet.NewRange(ipNet),
+			Data:   map[string]string{"country": item.country, "isp": ""},
+			Fields: meta.Fields,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return writer
+}
+
+func writeString(t *testing.T, writer *Writer) string {
+	buf := &bytes.Buffer{}
+	if _, err := writer.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriter(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(`# Generated by ips, grouped by country
+# CN
+create ips_CN hash:net family inet maxelem 65536 -exist
+flush ips_CN
+add ips_CN 1.0.0.0/24
+create ips_CN_v6 hash:net family inet6 maxelem 65536 -exist
+flush ips_CN_v6
+add ips_CN_v6 2001:db8::/32
+# US
+create ips_US hash:net family inet maxelem 65536 -exist
+flush ips_US
+add ips_US 2.0.0.0/24
+`, writeString(t, newTestWriter(t, FormatIPSet, WriterOption{})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+table inet geo {
+	# CN
+	set CN_v4 {
+		type ipv4_addr
+		flags interval
+		elements = {
+			1.0.0.0/24
+		}
+	}
+	# CN
+	set CN_v6 {
+		type ipv6_addr
+		flags interval
+		elements = {
+			2001:db8::/32
+		}
+	}
+}
+`, writeString(t, newTestWriter(t, FormatNftables, WriterOption{Name: "geo", Groups: []string{"CN"}})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+geo $ips {
+	default "";
+	1.0.0.0/24 "CN";
+	2001:db8::/32 "CN";
+	2.0.0.0/24 "US";
+}
+`, writeString(t, newTestWriter(t, FormatNginx, WriterOption{})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+1.0.0.0/24 CN
+2001:db8::/32 CN
+2.0.0.0/24 US
+`, writeString(t, newTestWriter(t, FormatHAProxy, WriterOption{})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+acl "ips_US" {
+	2.0.0.0/24;
+};
+`, writeString(t, newTestWriter(t, FormatBind, WriterOption{Groups: []string{"US"}})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+rules:
+  - IP-CIDR,1.0.0.0/24,PROXY,no-resolve
+  - IP-CIDR6,2001:db8::/32,PROXY,no-resolve
+  - IP-CIDR,2.0.0.0/24,PROXY,no-resolve
+`, writeString(t, newTestWriter(t, FormatClash, WriterOption{Policy: "PROXY"})))
+
+	ast.Equal(`# Generated by ips, grouped by country
+[Rule]
+IP-CIDR,1.0.0.0/24,CN,no-resolve
+IP-CIDR6,2001:db8::/32,CN,no-resolve
+IP-CIDR,2.0.0.0/24,US,no-resolve
+`, writeString(t, newTestWriter(t, FormatSurge, WriterOption{})))
+
+	// groups without any value are skipped
+	ast.Equal("# Generated by ips, grouped by isp\n", writeString(t, newTestWriter(t, FormatHAProxy, WriterOption{GroupBy: "isp"})))
+}
+
+func TestNewWriter(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{Fields: []string{"country"}}
+	_, err := NewWriter("unknown", meta)
+	ast.Equal(errors.ErrUnsupportedFormat, err)
+
+	writer, err := NewWriter(FormatIPSet, meta)
+	ast.Nil(err)
+	ast.Equal(FormatIPSet, writer.WriterFormat())
+	ast.Equal(errors.ErrFieldInvalid, writer.SetOption(WriterOption{GroupBy: "city"}))
+}
+
+func TestIdentifier(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal("CN", identifier("CN", 1))
+	ast.Equal("United_States", identifier("United States", 2))
+	ast.Equal("group3", identifier("中国", 3))
+
+	// values sharing an identifier are told apart by their indexes
+	ast.Equal([]string{"AS1", "AS1____2", "AS1____3", "AS1____4", "group5"},
+		identifiers([]string{"AS1", "AS1 电信", "AS1 移动", "AS1____4", "中国"}, 24))
+	ast.Equal([]string{"AS1____5", "AS1____2", "AS1____1", "AS1____4"},
+		identifiers([]string{"AS1 电信", "AS1 移动", "AS1____1", "AS1____4"}, 24))
+
+	// identifiers are cut to the length
+	ast.Equal([]string{"China_Te_1", "China_Te_2", "US"},
+		identifiers([]string{"China Telecom Guangdong", "China Telecom Guangxi", "US"}, 10))
+}
+
+func TestIPSetNames(t *testing.T) {
+	ast := assert.New(t)
+
+	meta := &model.Meta{Fields: []string{"isp"}}
+	writer, err := NewWriter(FormatIPSet, meta)
+	ast.Nil(err)
+	ast.Equal(errors.ErrNameTooLong, writer.SetOption(WriterOption{Name: "a_very_long_name_of_sets"}))
+	ast.Nil(writer.SetOption(WriterOption{Name: "isp_sets"}))
+
+	for _, item := range []struct {
+		cidr string
+		isp  string
+	}{
+		{"1.0.0.0/24", "AS4134 中国电信"},
+		{"2.0.0.0/24", "AS4134 中国移动"},
+		{"3.0.0.0/24", "AS15169 Google LLC, Mountain View"},
+	} {
+		_, ipNet, err := net.ParseCIDR(item.cidr)
+		ast.Nil(err)
+		ast.Nil(writer.Insert(&model.IPInfo{IP: ipNet.IP, IPNet: ipnet.NewRange(ipNet), Data: map[string]string{"isp": item.isp}}))
+	}
+	ast.Equal(`# Generated by ips, grouped by isp
+# AS15169 Google LLC, Mountain View
+create isp_sets_AS15169_Google_LLC_ hash:net family inet maxelem 65536 -exist
+flush isp_sets_AS15169_Google_LLC_
+add isp_sets_AS15169_Google_LLC_ 3.0.0.0/24
+# AS4134 中国电信
+create isp_sets_AS4134______2 hash:net family inet maxelem 65536 -exist
+flush isp_sets_AS4134______2
+add isp_sets_AS4134______2 1.0.0.0/24
+# AS4134 中国移动
+create isp_sets_AS4134______3 hash:net family inet maxelem 65536 -exist
+flush isp_sets_AS4134______3
+add isp_sets_AS4134______3 2.0.0.0/24
+`, writeString(t, writer))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/sjzar/ips/format/ipdb"
 	"github.com/sjzar/ips/format/mmdb"
 	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/format/ruleset"
 	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
@@ -61,6 +62,14 @@ var (
 		ipdb.DBFormat:  func(meta *model.Meta) (Writer, error) { return ipdb.NewWriter(meta) },
 		mmdb.DBFormat:  func(meta *model.Meta) (Writer, error) { return mmdb.NewWriter(meta) },
 		plain.DBFormat: func(meta *model.Meta) (Writer, error) { return plain.NewWriter(meta) },
+
+		ruleset.FormatIPSet:    func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatIPSet, meta) },
+		ruleset.FormatNftables: func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatNftables, meta) },
+		ruleset.FormatNginx:    func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatNginx, meta) },
+		ruleset.FormatHAProxy:  func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatHAProxy, meta) },
+		ruleset.FormatBind:     func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatBind, meta) },
+		ruleset.FormatClash:    func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatClash, meta) },
+		ruleset.FormatSurge:    func(meta *model.Meta) (Writer, error) { return ruleset.NewWriter(ruleset.FormatSurge, meta) },
 	}
 	WriterExts = map[string]func(meta *model.Meta) (Writer, error){
 		ipdb.DBExt:  func(meta *model.Meta) (Writer, error) { return ipdb.NewWriter(meta) },
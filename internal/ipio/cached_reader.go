@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"container/list"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// CachedReader is a Reader caching the IP information found by another reader in an LRU cache.
+// The cache is keyed by the network range of the IP information rather than the IP address,
+// so any IP address in a cached range is found in the cache.
+// The range is indexed by the CIDRs covering it, and an IP address is looked up by each prefix
+// length in the cache, instead of by every cached range.
+type CachedReader struct {
+	format.Reader
+
+	// OnFind, if set, is called with whether the IP was found in the cache.
+	OnFind func(hit bool)
+
+	size    int
+	mu      sync.Mutex
+	entries *list.List                     // Cached IP information, the most recently used first.
+	index   map[netip.Prefix]*list.Element // Cached IP information by the CIDRs covering its range.
+	bits    [129]int                       // Number of CIDRs in the index by prefix length.
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// cacheEntry is the IP information cached with the CIDRs covering its range.
+type cacheEntry struct {
+	info     *model.IPInfo
+	prefixes []netip.Prefix
+}
+
+// CacheStats describes the usage of a CachedReader.
+type CacheStats struct {
+	Size    int     `json:"size"`
+	Entries int     `json:"entries"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// NewCachedReader creates a CachedReader of the reader, caching the IP information of up to size ranges.
+func NewCachedReader(reader format.Reader, size int) *CachedReader {
+	return &CachedReader{
+		Reader:  reader,
+		size:    size,
+		entries: list.New(),
+		index:   make(map[netip.Prefix]*list.Element),
+	}
+}
+
+// Find retrieves IP information based on the given IP address, from the cache if its range is cached.
+// IP information without a network range is not cached.
+func (c *CachedReader) Find(ip net.IP) (*model.IPInfo, error) {
+	addr, ok := cacheAddr(ip)
+	if !ok {
+		return c.Reader.Find(ip)
+	}
+
+	if info := c.get(addr); info != nil {
+		c.hits.Add(1)
+		if c.OnFind != nil {
+			c.OnFind(true)
+		}
+		return copyIPInfo(info, ip), nil
+	}
+	c.misses.Add(1)
+	if c.OnFind != nil {
+		c.OnFind(false)
+	}
+
+	info, err := c.Reader.Find(ip)
+	if err != nil {
+		return nil, err
+	}
+	if info.IPNet != nil {
+		c.add(copyIPInfo(info, ip))
+	}
+	return info, nil
+}
+
+// SetOption configures the underlying reader, and purges the cache as the IP information may change.
+func (c *CachedReader) SetOption(option interface{}) error {
+	defer c.Purge()
+	return c.Reader.SetOption(option)
+}
+
+// Close purges the cache and closes the underlying reader.
+func (c *CachedReader) Close() error {
+	c.Purge()
+	return c.Reader.Close()
+}
+
+// Purge removes all the cached IP information.
+func (c *CachedReader) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Init()
+	c.index = make(map[netip.Prefix]*list.Element)
+	c.bits = [129]int{}
+}
+
+// Stats returns the usage of the cache.
+func (c *CachedReader) Stats() *CacheStats {
+	c.mu.Lock()
+	entries := c.entries.Len()
+	c.mu.Unlock()
+
+	stats := &CacheStats{
+		Size:    c.size,
+		Entries: entries,
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// get returns the cached IP information of the range containing the address, or nil if not cached.
+func (c *CachedReader) get(addr netip.Addr) *model.IPInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for bits := 128; bits >= 0; bits-- {
+		if c.bits[bits] == 0 {
+			continue
+		}
+		prefix, _ := addr.Prefix(bits)
+		if elem, ok := c.index[prefix]; ok {
+			c.entries.MoveToFront(elem)
+			return elem.Value.(*cacheEntry).info
+		}
+	}
+	return nil
+}
+
+// add caches the IP information, evicting the least recently used ones if the cache is full.
+// The IP information cached with the same CIDRs is replaced.
+func (c *CachedReader) add(info *model.IPInfo) {
+	prefixes := rangePrefixes(info.IPNet)
+	if len(prefixes) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, prefix := range prefixes {
+		if elem, ok := c.index[prefix]; ok {
+			c.remove(elem)
+		}
+	}
+	for c.entries.Len() >= c.size && c.entries.Len() > 0 {
+		c.remove(c.entries.Back())
+	}
+
+	elem := c.entries.PushFront(&cacheEntry{info: info, prefixes: prefixes})
+	for _, prefix := range prefixes {
+		c.index[prefix] = elem
+		c.bits[prefix.Bits()]++
+	}
+}
+
+// remove removes the cached IP information of the element.
+func (c *CachedReader) remove(elem *list.Element) {
+	entry := c.entries.Remove(elem).(*cacheEntry)
+	for _, prefix := range entry.prefixes {
+		if c.index[prefix] == elem {
+			delete(c.index, prefix)
+			c.bits[prefix.Bits()]--
+		}
+	}
+}
+
+// cacheAddr returns the address in the 16-byte form, so that IPv4 and IPv4-mapped IPv6 addresses are the same.
+func cacheAddr(ip net.IP) (netip.Addr, bool) {
+	return netip.AddrFromSlice(ip.To16())
+}
+
+// rangePrefixes returns the CIDRs covering the range, in the 16-byte form.
+func rangePrefixes(r *ipnet.Range) []netip.Prefix {
+	ipNets := r.IPNets()
+	ret := make([]netip.Prefix, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		addr, ok := cacheAddr(ipNet.IP)
+		if !ok {
+			return nil
+		}
+		ones, bits := ipNet.Mask.Size()
+		if bits == 0 {
+			return nil
+		}
+		ret = append(ret, netip.PrefixFrom(addr, ones+128-bits))
+	}
+	return ret
+}
+
+// copyIPInfo returns a copy of the IP information for the IP address.
+// The data is copied, and the other fields are shared as they are not changed after the lookup.
+func copyIPInfo(info *model.IPInfo, ip net.IP) *model.IPInfo {
+	ret := *info
+	ret.IP = ip
+	ret.Data = make(map[string]string, len(info.Data))
+	for k, v := range info.Data {
+		ret.Data[k] = v
+	}
+	return &ret
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ipio
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// rangeReader finds the IP information of fixed ranges, counting the lookups.
+type rangeReader struct {
+	ranges []*ipnet.Range
+	finds  int
+}
+
+func (r *rangeReader) Meta() *model.Meta { return &model.Meta{} }
+
+func (r *rangeReader) Find(ip net.IP) (*model.IPInfo, error) {
+	r.finds++
+	for _, rg := range r.ranges {
+		if rg.Contains(ip) {
+			return &model.IPInfo{IP: ip, IPNet: rg, Data: map[string]string{"start": rg.Start.String()}}, nil
+		}
+	}
+	return &model.IPInfo{IP: ip, Data: map[string]string{}}, nil
+}
+
+func (r *rangeReader) SetOption(interface{}) error { return nil }
+
+func (r *rangeReader) Close() error { return nil }
+
+func TestCachedReader(t *testing.T) {
+	ast := assert.New(t)
+
+	db := &rangeReader{ranges: []*ipnet.Range{
+		{Start: net.ParseIP("1.0.0.0").To16(), End: net.ParseIP("1.0.2.255").To16()},
+		{Start: net.ParseIP("2.0.0.0").To16(), End: net.ParseIP("2.0.0.255").To16()},
+		{Start: net.ParseIP("3.0.0.0").To16(), End: net.ParseIP("3.0.0.255").To16()},
+	}}
+	reader := NewCachedReader(db, 2)
+
+	info, err := reader.Find(net.ParseIP("1.0.0.1"))
+	ast.Nil(err)
+	ast.Equal("1.0.0.0", info.Data["start"])
+
+	// any IP in the cached range hits, including the CIDRs not containing the first IP
+	info, err = reader.Find(net.ParseIP("1.0.2.200").To4())
+	ast.Nil(err)
+	ast.Equal("1.0.2.200", info.IP.String())
+	ast.Equal("1.0.0.0", info.Data["start"])
+	ast.Equal(1, db.finds)
+
+	// IP information without a range is not cached
+	_, _ = reader.Find(net.ParseIP("9.9.9.9"))
+	_, _ = reader.Find(net.ParseIP("9.9.9.9"))
+	ast.Equal(3, db.finds)
+
+	// the least recently used range is evicted
+	_, _ = reader.Find(net.ParseIP("2.0.0.1"))
+	_, _ = reader.Find(net.ParseIP("1.0.1.1"))
+	_, _ = reader.Find(net.ParseIP("3.0.0.1"))
+	ast.Equal(5, db.finds)
+	_, _ = reader.Find(net.ParseIP("1.0.0.2"))
+	ast.Equal(5, db.finds)
+	_, _ = reader.Find(net.ParseIP("2.0.0.2"))
+	ast.Equal(6, db.finds)
+
+	stats := reader.Stats()
+	ast.Equal(2, stats.Entries)
+	ast.Equal(uint64(3), stats.Hits)
+	ast.Equal(uint64(6), stats.Misses)
+	ast.InDelta(1.0/3, stats.HitRate, 1e-9)
+
+	// the cached data is not changed by the callers
+	info.Data["start"] = "changed"
+	info, _ = reader.Find(net.ParseIP("2.0.0.3"))
+	ast.Equal("2.0.0.0", info.Data["start"])
+
+	reader.Purge()
+	ast.Equal(0, reader.Stats().Entries)
+	_, _ = reader.Find(net.ParseIP("2.0.0.3"))
+	ast.Equal(7, db.finds)
+}
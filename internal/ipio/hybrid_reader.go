@@ -56,6 +56,9 @@ type HybridReader struct {
 	dbReaders    []format.Reader         // Collection of database readers.
 	meta         *model.Meta             // Combined metadata from all readers.
 	hybridMode   string                  // Operational mode of the HybridReader.
+
+	// OnFind, if set, is called with the index of each reader and whether it found data for the IP.
+	OnFind func(index int, hit bool)
 }
 
 // NewHybridReader constructs a new HybridReader with the provided IP operation chain and database readers.
@@ -140,6 +143,12 @@ func (h *HybridReader) Find(ip net.IP) (*model.IPInfo, error) {
 
 	wg.Wait()
 
+	if h.OnFind != nil {
+		for i := range results {
+			h.OnFind(i, errs[i] == nil && results[i] != nil && hasData(results[i]))
+		}
+	}
+
 	// Check for errors and combine results
 	hybridIPInfo := &model.IPInfo{
 		IP:            ip,
@@ -223,3 +232,13 @@ func (h *HybridReader) Close() error {
 	}
 	return nil
 }
+
+// hasData checks whether the IP information contains any non-empty value.
+func hasData(info *model.IPInfo) bool {
+	for _, value := range info.Data {
+		if len(value) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// BatchFlushSize is the number of batch results written between flushes of the response.
+const BatchFlushSize = 100
+
+// BatchReader reads the IPs of a batch request from either a JSON array or an NDJSON stream.
+// Items are read one at a time, so a huge batch is never held in memory.
+//
+// An item is a JSON string or an object with an "ip" key, e.g.
+// ["1.1.1.1", {"ip": "8.8.8.8"}]
+// NDJSON lines may also be plain IPs.
+type BatchReader struct {
+	// Array reports whether the batch is a JSON array, otherwise it is an NDJSON stream.
+	Array bool
+
+	decoder *json.Decoder
+	scanner *bufio.Scanner
+}
+
+// NewBatchReader detects the batch type by the first non-space byte of r and returns a BatchReader.
+func NewBatchReader(r io.Reader) (*BatchReader, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, errors.ErrInvalidBatch
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+			continue
+		case '[':
+			decoder := json.NewDecoder(br)
+			if _, err := decoder.Token(); err != nil {
+				return nil, errors.ErrInvalidBatch
+			}
+			return &BatchReader{Array: true, decoder: decoder}, nil
+		}
+		return &BatchReader{scanner: bufio.NewScanner(br)}, nil
+	}
+}
+
+// Next returns the next IP of the batch, or io.EOF at the end of the batch.
+// Items that are not IPs are returned as is, to be reported by the lookup.
+// A malformed batch returns ErrInvalidBatch.
+func (r *BatchReader) Next() (string, error) {
+	if r.Array {
+		if !r.decoder.More() {
+			if _, err := r.decoder.Token(); err != nil {
+				return "", errors.ErrInvalidBatch
+			}
+			return "", io.EOF
+		}
+		var item json.RawMessage
+		if err := r.decoder.Decode(&item); err != nil {
+			return "", errors.ErrInvalidBatch
+		}
+		return parseBatchItem(item), nil
+	}
+
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return parseBatchItem(line), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", errors.ErrInvalidBatch
+	}
+	return "", io.EOF
+}
+
+// parseBatchItem returns the IP of a batch item.
+func parseBatchItem(item []byte) string {
+	switch item[0] {
+	case '"':
+		var ip string
+		if err := json.Unmarshal(item, &ip); err == nil {
+			return ip
+		}
+	case '{':
+		var obj struct {
+			IP string `json:"ip"`
+		}
+		if err := json.Unmarshal(item, &obj); err == nil {
+			return obj.IP
+		}
+	}
+	return string(item)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestBatchReader(t *testing.T) {
+	ast := assert.New(t)
+
+	readAll := func(r *BatchReader) ([]string, error) {
+		ret := make([]string, 0)
+		for {
+			ip, err := r.Next()
+			if err == io.EOF {
+				return ret, nil
+			}
+			if err != nil {
+				return ret, err
+			}
+			ret = append(ret, ip)
+		}
+	}
+
+	r, err := NewBatchReader(strings.NewReader(` ["1.1.1.1", {"ip": "::1"}, 12] `))
+	ast.Nil(err)
+	ast.True(r.Array)
+	ips, err := readAll(r)
+	ast.Nil(err)
+	ast.Equal([]string{"1.1.1.1", "::1", "12"}, ips)
+
+	r, err = NewBatchReader(strings.NewReader("1.1.1.1\n\n\"8.8.8.8\"\r\n{\"ip\": \"::1\"}"))
+	ast.Nil(err)
+	ast.False(r.Array)
+	ips, err = readAll(r)
+	ast.Nil(err)
+	ast.Equal([]string{"1.1.1.1", "8.8.8.8", "::1"}, ips)
+
+	r, err = NewBatchReader(strings.NewReader(`["1.1.1.1", `))
+	ast.Nil(err)
+	ips, err = readAll(r)
+	ast.Equal(errors.ErrInvalidBatch, err)
+	ast.Equal([]string{"1.1.1.1"}, ips)
+
+	_, err = NewBatchReader(strings.NewReader(" \n"))
+	ast.Equal(errors.ErrInvalidBatch, err)
+}
+
+func TestPostBatch(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := newTestManager(t, "country,city")
+	router := gin.New()
+	router.POST("/batch", m.PostBatch)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+		return w
+	}
+
+	// NDJSON is streamed line by line, a failed item does not stop the batch
+	w := do("200.1.1.1\nbogus\n{\"ip\": \"130.0.0.1\"}\n")
+	ast.Equal(http.StatusOK, w.Code)
+	ast.Equal("application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if ast.Len(lines, 3) {
+		ast.JSONEq(`{"ip": "200.1.1.1", "net": "192.0.0.0/3", "data": {"country": "中国", "city": "深圳"}}`, lines[0])
+		ast.JSONEq(`{"ip": "bogus", "error": "invalid IP address"}`, lines[1])
+		ast.JSONEq(`{"ip": "130.0.0.1", "net": "128.0.0.0/2", "data": {"country": "美国", "city": ""}}`, lines[2])
+	}
+
+	// a JSON array is answered by a JSON array
+	w = do(`["200.1.1.1", "bogus"]`)
+	ast.Equal(http.StatusOK, w.Code)
+	var items []map[string]interface{}
+	ast.Nil(json.Unmarshal(w.Body.Bytes(), &items))
+	if ast.Len(items, 2) {
+		ast.Equal("200.1.1.1", items[0]["ip"])
+		ast.Equal("invalid IP address", items[1]["error"])
+	}
+
+	// an oversized batch ends with an error item
+	m.Conf.BatchMaxSize = 2
+	w = do(`["200.1.1.1", "130.0.0.1", "1.1.1.1", "1.1.1.2"]`)
+	ast.Equal(http.StatusOK, w.Code)
+	items = nil
+	ast.Nil(json.Unmarshal(w.Body.Bytes(), &items))
+	if ast.Len(items, 3) {
+		ast.Equal("130.0.0.1", items[1]["ip"])
+		ast.Equal(map[string]interface{}{"error": errors.ErrBatchTooLarge.Error()}, items[2])
+	}
+
+	// a malformed array ends with an error item
+	w = do(`["200.1.1.1", `)
+	items = nil
+	ast.Nil(json.Unmarshal(w.Body.Bytes(), &items))
+	if ast.Len(items, 2) {
+		ast.Equal(map[string]interface{}{"error": errors.ErrInvalidBatch.Error()}, items[1])
+	}
+
+	// an empty batch is rejected
+	w = do(" \n")
+	ast.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/pires/go-proxyproto"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// ClientIPResolver resolves the client IP address of a request.
+// Forwarding headers are honored only when the request comes from a trusted proxy,
+// in the order of Forwarded, X-Forwarded-For and X-Real-IP.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver creates a ClientIPResolver with the trusted proxies, given as IP addresses or CIDRs.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientIPResolver{trusted: trusted}, nil
+}
+
+// IsTrusted checks whether the address is a trusted proxy.
+// A nil resolver trusts no proxy.
+func (r *ClientIPResolver) IsTrusted(addr netip.Addr) bool {
+	if r == nil {
+		return false
+	}
+	return containsAddr(r.trusted, addr)
+}
+
+// ClientIP returns the client IP address of the request.
+// The forwarding chain is walked from the nearest hop, and the first address
+// that is not a trusted proxy is the client.
+// A peer without an IP address, i.e. on a unix socket, is a local proxy and always trusted.
+func (r *ClientIPResolver) ClientIP(req *http.Request) (netip.Addr, error) {
+	remote, err := parseHostAddr(req.RemoteAddr)
+	if err == nil && !r.IsTrusted(remote) {
+		return remote, nil
+	}
+
+	chain := forwardedFor(req.Header.Values("Forwarded"))
+	if len(chain) == 0 {
+		for _, value := range req.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(hop))
+			}
+		}
+	}
+	if len(chain) == 0 {
+		if realIP := strings.TrimSpace(req.Header.Get("X-Real-IP")); len(realIP) != 0 {
+			chain = append(chain, realIP)
+		}
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, err := parseHostAddr(chain[i])
+		if err != nil {
+			// an unknown or obfuscated hop can not be followed
+			break
+		}
+		client = addr
+		if !r.IsTrusted(addr) {
+			break
+		}
+	}
+	if !client.IsValid() {
+		return netip.Addr{}, errors.ErrInvalidIP
+	}
+	return client, nil
+}
+
+// ProxyProtocolPolicy returns the PROXY protocol policy of the listener.
+// Headers are used only from the trusted proxies and unix sockets, and ignored from others,
+// so that a client can not set its own address. To accept the headers from any upstream,
+// trust all addresses explicitly, i.e. "0.0.0.0/0" and "::/0".
+func (r *ClientIPResolver) ProxyProtocolPolicy(upstream net.Addr) (proxyproto.Policy, error) {
+	if upstream.Network() == "unix" {
+		return proxyproto.USE, nil
+	}
+	addr, err := parseHostAddr(upstream.String())
+	if err != nil || !r.IsTrusted(addr) {
+		return proxyproto.IGNORE, nil
+	}
+	return proxyproto.USE, nil
+}
+
+// parsePrefixes parses the IP addresses or CIDRs into prefixes.
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	ret := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				log.Debug("netip.ParseAddr error: ", err)
+				return nil, errors.ErrInvalidCIDR
+			}
+			ret = append(ret, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			log.Debug("netip.ParsePrefix error: ", err)
+			return nil, errors.ErrInvalidCIDR
+		}
+		ret = append(ret, prefix.Masked())
+	}
+	return ret, nil
+}
+
+// containsAddr checks whether the address is in one of the prefixes.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the "for" parameters of the Forwarded headers (RFC 7239), in order.
+func forwardedFor(values []string) []string {
+	ret := make([]string, 0)
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				ret = append(ret, strings.Trim(val, `"`))
+			}
+		}
+	}
+	return ret
+}
+
+// parseHostAddr parses an IP address with an optional port, e.g. "1.1.1.1", "1.1.1.1:80", "[::1]:80" or "[::1]".
+func parseHostAddr(s string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	ast := assert.New(t)
+
+	_, err := NewClientIPResolver([]string{"10.0.0.0/33"})
+	ast.ErrorIs(err, errors.ErrInvalidCIDR)
+	_, err = NewClientIPResolver([]string{"proxy"})
+	ast.ErrorIs(err, errors.ErrInvalidCIDR)
+
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "::1"})
+	ast.Nil(err)
+
+	testCases := []struct {
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		// untrusted remote, headers are ignored
+		{"1.1.1.1:1234", map[string]string{"X-Forwarded-For": "2.2.2.2"}, "1.1.1.1"},
+		{"[2001:db8::1]:1234", map[string]string{"X-Real-IP": "2.2.2.2"}, "2001:db8::1"},
+		// trusted remote without headers
+		{"10.0.0.1:1234", nil, "10.0.0.1"},
+		{"[::1]:1234", nil, "::1"},
+		// X-Forwarded-For, trusted hops are skipped from the right
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "3.3.3.3, 2.2.2.2, 10.0.0.2"}, "2.2.2.2"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"10.0.0.1:1234", map[string]string{"X-Forwarded-For": "2.2.2.2, unknown"}, "10.0.0.1"},
+		// X-Real-IP
+		{"10.0.0.1:1234", map[string]string{"X-Real-IP": "2.2.2.2"}, "2.2.2.2"},
+		// Forwarded takes precedence
+		{"10.0.0.1:1234", map[string]string{"Forwarded": "for=2.2.2.2;proto=https", "X-Forwarded-For": "3.3.3.3"}, "2.2.2.2"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8::2]:4711", for=10.0.0.2`}, "2001:db8::2"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": `For="2.2.2.2:80"`}, "2.2.2.2"},
+		{"10.0.0.1:1234", map[string]string{"Forwarded": "for=_hidden, for=2.2.2.2"}, "2.2.2.2"},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/myip", nil)
+		req.RemoteAddr = tc.remote
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		addr, err := resolver.ClientIP(req)
+		ast.Nil(err, tc.remote)
+		ast.Equal(tc.want, addr.String(), tc.headers)
+	}
+
+	// unix socket peers are local proxies
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/myip", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("X-Forwarded-For", "2.2.2.2")
+	addr, err := resolver.ClientIP(req)
+	ast.Nil(err)
+	ast.Equal("2.2.2.2", addr.String())
+	req.Header.Del("X-Forwarded-For")
+	_, err = resolver.ClientIP(req)
+	ast.ErrorIs(err, errors.ErrInvalidIP)
+
+	// nil resolver trusts no proxy
+	var nilResolver *ClientIPResolver
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/myip", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "2.2.2.2")
+	addr, err = nilResolver.ClientIP(req)
+	ast.Nil(err)
+	ast.Equal("10.0.0.1", addr.String())
+}
+
+func TestProxyProtocolPolicy(t *testing.T) {
+	ast := assert.New(t)
+
+	tcp := func(s string) net.Addr {
+		return net.TCPAddrFromAddrPort(netip.MustParseAddrPort(s))
+	}
+	unix := &net.UnixAddr{Name: "@", Net: "unix"}
+
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8"})
+	ast.Nil(err)
+	for upstream, expected := range map[net.Addr]proxyproto.Policy{
+		tcp("10.0.0.1:1234"): proxyproto.USE,
+		tcp("1.1.1.1:1234"):  proxyproto.IGNORE,
+		unix:                 proxyproto.USE,
+	} {
+		policy, err := resolver.ProxyProtocolPolicy(upstream)
+		ast.Nil(err)
+		ast.Equal(expected, policy, upstream.String())
+	}
+
+	// no trusted proxy accepts the headers only from unix sockets
+	for _, resolver := range []*ClientIPResolver{nil, {}} {
+		policy, _ := resolver.ProxyProtocolPolicy(tcp("1.1.1.1:1234"))
+		ast.Equal(proxyproto.IGNORE, policy)
+		policy, _ = resolver.ProxyProtocolPolicy(unix)
+		ast.Equal(proxyproto.USE, policy)
+	}
+
+	// any upstream is trusted explicitly
+	resolver, err = NewClientIPResolver([]string{"0.0.0.0/0", "::/0"})
+	ast.Nil(err)
+	policy, _ := resolver.ProxyProtocolPolicy(tcp("1.1.1.1:1234"))
+	ast.Equal(proxyproto.USE, policy)
+	policy, _ = resolver.ProxyProtocolPolicy(tcp("[2001:db8::1]:1234"))
+	ast.Equal(proxyproto.USE, policy)
+}
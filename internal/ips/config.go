@@ -18,6 +18,7 @@ package ips
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -36,6 +37,21 @@ const (
 	// OutputTypeAlfred represents the Alfred output format.
 	OutputTypeAlfred = "alfred"
 
+	// OutputTypeCSV represents the CSV output format, with a header row of the fields.
+	OutputTypeCSV = "csv"
+
+	// OutputTypeTSV represents the TSV output format, with a header row of the fields.
+	OutputTypeTSV = "tsv"
+
+	// OutputTypeNDJSON represents the NDJSON output format, one JSON object per IP or domain.
+	OutputTypeNDJSON = "ndjson"
+
+	// OutputTypeYAML represents the YAML output format.
+	OutputTypeYAML = "yaml"
+
+	// OutputTypeTable represents the table output format, rendered after all the results.
+	OutputTypeTable = "table"
+
 	// DefaultFields represents the default output fields.
 	DefaultFields = "country,province,city,isp"
 )
@@ -81,6 +97,7 @@ type Config struct {
 	RewriteFiles string `mapstructure:"rewrite_files"`
 
 	// OutputType specifies the type of the output. (default is text)
+	// Accepted values are "text", "json", "alfred", "csv", "tsv", "ndjson", "yaml" and "table".
 	OutputType string `mapstructure:"output_type"`
 
 	// TextFormat specifies the format for text output.
@@ -90,6 +107,10 @@ type Config struct {
 	// TextValuesSep specifies the separator for values in text output. (default is space)
 	TextValuesSep string `mapstructure:"text_values_sep" default:" "`
 
+	// TextTemplate specifies the text/template of each IP and domain in text output.
+	// It takes precedence over TextFormat.
+	TextTemplate string `mapstructure:"text_template"`
+
 	// JsonIndent indicates whether the JSON output should be indented.
 	JsonIndent bool `mapstructure:"json_indent"`
 
@@ -101,6 +122,11 @@ type Config struct {
 	// DPRewriterFiles lists the files for rewriting during dump and pack operations.
 	DPRewriterFiles string `mapstructure:"dp_rewriter_files"`
 
+	// Download
+	// DownloadSources lists the custom download sources of database files.
+	// They override the predefined sources with the same name.
+	DownloadSources []*DownloadSource `mapstructure:"download_sources"`
+
 	// Database
 	// ReaderOption specifies the options for the reader.
 	ReaderOption string `mapstructure:"reader_option"`
@@ -112,6 +138,10 @@ type Config struct {
 	// It controls how many reading operations can be performed in parallel.
 	ReaderJobs int `mapstructure:"reader_jobs"`
 
+	// CacheSize specifies the number of IP ranges cached by each reader of the lookups.
+	// The cache is disabled if not positive.
+	CacheSize int `mapstructure:"cache_size"`
+
 	// MyIP
 	// LocalAddr specifies the local address (in IP format) that should be used for outbound connections.
 	// Useful in systems with multiple network interfaces.
@@ -145,9 +175,73 @@ type Config struct {
 	// MDNSRetryTimes sets the number of times an MDNS query should be retried on failure.
 	MDNSRetryTimes int `mapstructure:"mdns_retry_times" default:"3"`
 
+	// DNS Server
+	// DNSAddr specifies the address for the DNS server.
+	DNSAddr string `mapstructure:"dns_addr" default:":5353"`
+
+	// DNSZone specifies the zone answered by the DNS server.
+	DNSZone string `mapstructure:"dns_zone" default:"geo.local"`
+
+	// DNSTTL specifies the TTL in seconds of the DNS answers.
+	DNSTTL int `mapstructure:"dns_ttl" default:"60"`
+
 	// Service
-	// Addr specifies the address for the service.
+	// Addr specifies the address for the service. Multiple addresses are separated by commas,
+	// and a unix socket address starts with "unix:", e.g. ":6860,unix:/run/ips.sock".
 	Addr string `mapstructure:"addr" default:":6860"`
+
+	// TLSCert and TLSKey specify the certificate and key files to serve HTTPS.
+	// The certificate is reloaded when the files change.
+	TLSCert string `mapstructure:"tls_cert"`
+	TLSKey  string `mapstructure:"tls_key"`
+
+	// ShutdownTimeoutS specifies the maximum duration (in seconds) to wait for the requests in flight on shutdown.
+	ShutdownTimeoutS int `mapstructure:"shutdown_timeout_s" default:"30"`
+
+	// TrustedProxies lists the IPs or CIDRs of the trusted proxies, whose forwarding headers
+	// and PROXY protocol headers are used to resolve the client IP.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// ProxyProtocol indicates whether the service accepts PROXY protocol v1/v2 headers.
+	ProxyProtocol bool `mapstructure:"proxy_protocol"`
+
+	// GRPCAddr specifies the address for the gRPC service. The gRPC service is disabled if empty.
+	GRPCAddr string `mapstructure:"grpc_addr"`
+
+	// BatchMaxSize specifies the maximum number of IPs in a batch lookup request.
+	BatchMaxSize int `mapstructure:"batch_max_size" default:"10000"`
+
+	// Metrics indicates whether to expose Prometheus metrics on /metrics.
+	Metrics bool `mapstructure:"metrics"`
+
+	// APIKeys lists the API keys accepted by the API. The API requires no key if empty.
+	APIKeys []string `mapstructure:"api_keys"`
+
+	// RateLimitPerKey specifies the requests per second allowed for each API key. No limit if not positive.
+	RateLimitPerKey float64 `mapstructure:"rate_limit_per_key"`
+
+	// RateLimitPerIP specifies the requests per second allowed for each client IP. No limit if not positive.
+	RateLimitPerIP float64 `mapstructure:"rate_limit_per_ip"`
+
+	// RateLimitBurst specifies the maximum burst of requests of the rate limits.
+	// It defaults to the rate limit rounded up.
+	RateLimitBurst int `mapstructure:"rate_limit_burst"`
+
+	// CORSAllowOrigins lists the origins allowed by the CORS policy, "*" for any origin.
+	// CORS is disabled if empty.
+	CORSAllowOrigins []string `mapstructure:"cors_allow_origins"`
+
+	// AllowIPs lists the IPs or CIDRs of the clients allowed to access the service.
+	// Every client is allowed if empty.
+	AllowIPs []string `mapstructure:"allow_ips"`
+
+	// Profiles defines the named profiles selected by the "profile" query parameter of the API,
+	// each overriding the databases and output options.
+	Profiles map[string]*Profile `mapstructure:"profiles"`
+
+	// GeoIP2Accounts maps the account IDs to the license keys accepted by the GeoIP2 compatible API.
+	// The API requires no authentication if empty.
+	GeoIP2Accounts map[string]string `mapstructure:"geoip2_accounts"`
 }
 
 func (c *Config) ShowConfig(allKeys bool) string {
@@ -184,6 +278,9 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || len(c.TextValuesSep) > 0 {
 		str += fmt.Sprintf("text_values_sep:\t[%s]\n", c.TextValuesSep)
 	}
+	if allKeys || len(c.TextTemplate) > 0 {
+		str += fmt.Sprintf("text_template:\t\t[%s]\n", c.TextTemplate)
+	}
 	if allKeys || c.JsonIndent {
 		str += fmt.Sprintf("json_indent:\t\t[%v]\n", c.JsonIndent)
 	}
@@ -193,12 +290,24 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || len(c.DPRewriterFiles) > 0 {
 		str += fmt.Sprintf("dp_rewriter_files:\t[%s]\n", c.DPRewriterFiles)
 	}
+	if allKeys || len(c.DownloadSources) > 0 {
+		names := make([]string, 0, len(c.DownloadSources))
+		for _, source := range c.DownloadSources {
+			if source != nil {
+				names = append(names, source.Name)
+			}
+		}
+		str += fmt.Sprintf("download_sources:\t[%s]\n", strings.Join(names, ","))
+	}
 	if allKeys || len(c.ReaderOption) > 0 {
 		str += fmt.Sprintf("reader_option:\t\t[%s]\n", c.ReaderOption)
 	}
 	if allKeys || len(c.WriterOption) > 0 {
 		str += fmt.Sprintf("writer_option:\t\t[%s]\n", c.WriterOption)
 	}
+	if allKeys || c.CacheSize > 0 {
+		str += fmt.Sprintf("cache_size:\t\t[%d]\n", c.CacheSize)
+	}
 	if allKeys || len(c.LocalAddr) > 0 {
 		str += fmt.Sprintf("local_addr:\t\t[%s]\n", c.LocalAddr)
 	}
@@ -208,9 +317,76 @@ func (c *Config) ShowConfig(allKeys bool) string {
 	if allKeys || c.MyIPTimeoutS > 0 {
 		str += fmt.Sprintf("myip_timeout_s:\t\t[%d]\n", c.MyIPTimeoutS)
 	}
+	if allKeys || len(c.DNSAddr) > 0 {
+		str += fmt.Sprintf("dns_addr:\t\t[%s]\n", c.DNSAddr)
+	}
+	if allKeys || len(c.DNSZone) > 0 {
+		str += fmt.Sprintf("dns_zone:\t\t[%s]\n", c.DNSZone)
+	}
+	if allKeys || c.DNSTTL > 0 {
+		str += fmt.Sprintf("dns_ttl:\t\t[%d]\n", c.DNSTTL)
+	}
 	if allKeys || len(c.Addr) > 0 {
 		str += fmt.Sprintf("addr:\t\t\t[%s]\n", c.Addr)
 	}
+	if allKeys || len(c.TLSCert) > 0 {
+		str += fmt.Sprintf("tls_cert:\t\t[%s]\n", c.TLSCert)
+	}
+	if allKeys || len(c.TLSKey) > 0 {
+		str += fmt.Sprintf("tls_key:\t\t[%s]\n", c.TLSKey)
+	}
+	if allKeys || c.ShutdownTimeoutS > 0 {
+		str += fmt.Sprintf("shutdown_timeout_s:\t[%d]\n", c.ShutdownTimeoutS)
+	}
+	if allKeys || len(c.TrustedProxies) > 0 {
+		str += fmt.Sprintf("trusted_proxies:\t[%s]\n", strings.Join(c.TrustedProxies, ","))
+	}
+	if allKeys || c.ProxyProtocol {
+		str += fmt.Sprintf("proxy_protocol:\t\t[%v]\n", c.ProxyProtocol)
+	}
+	if allKeys || len(c.GRPCAddr) > 0 {
+		str += fmt.Sprintf("grpc_addr:\t\t[%s]\n", c.GRPCAddr)
+	}
+	if allKeys || c.BatchMaxSize > 0 {
+		str += fmt.Sprintf("batch_max_size:\t\t[%d]\n", c.BatchMaxSize)
+	}
+	if allKeys || c.Metrics {
+		str += fmt.Sprintf("metrics:\t\t[%v]\n", c.Metrics)
+	}
+	if allKeys || len(c.APIKeys) > 0 {
+		str += fmt.Sprintf("api_keys:\t\t[%d keys]\n", len(c.APIKeys))
+	}
+	if allKeys || c.RateLimitPerKey > 0 {
+		str += fmt.Sprintf("rate_limit_per_key:\t[%v]\n", c.RateLimitPerKey)
+	}
+	if allKeys || c.RateLimitPerIP > 0 {
+		str += fmt.Sprintf("rate_limit_per_ip:\t[%v]\n", c.RateLimitPerIP)
+	}
+	if allKeys || c.RateLimitBurst > 0 {
+		str += fmt.Sprintf("rate_limit_burst:\t[%d]\n", c.RateLimitBurst)
+	}
+	if allKeys || len(c.CORSAllowOrigins) > 0 {
+		str += fmt.Sprintf("cors_allow_origins:\t[%s]\n", strings.Join(c.CORSAllowOrigins, ","))
+	}
+	if allKeys || len(c.AllowIPs) > 0 {
+		str += fmt.Sprintf("allow_ips:\t\t[%s]\n", strings.Join(c.AllowIPs, ","))
+	}
+	if allKeys || len(c.Profiles) > 0 {
+		profiles := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			profiles = append(profiles, name)
+		}
+		sort.Strings(profiles)
+		str += fmt.Sprintf("profiles:\t\t[%s]\n", strings.Join(profiles, ","))
+	}
+	if allKeys || len(c.GeoIP2Accounts) > 0 {
+		accounts := make([]string, 0, len(c.GeoIP2Accounts))
+		for account := range c.GeoIP2Accounts {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		str += fmt.Sprintf("geoip2_accounts:\t[%s]\n", strings.Join(accounts, ","))
+	}
 
 	return str
 }
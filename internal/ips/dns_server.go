@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+/*
+DNS Server
+
+The DNS server answers TXT queries of IP addresses under the configured zone, e.g. with the zone geo.local:
+
+IPv4:          8.8.8.8.geo.local
+IPv4 reversed: 8.8.4.4.in-addr.geo.local
+IPv6:          2001-db8--1.geo.local (colons replaced by dashes)
+IPv6 nibble:   1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.geo.local
+
+Each selected field is answered as a "field=value" string of the TXT record.
+
+*/
+
+const (
+	// DNSReversedIPv4Label marks a reversed IPv4 query, like in-addr.arpa.
+	DNSReversedIPv4Label = "in-addr"
+
+	// DNSNibbleIPv6Label marks a reversed nibble IPv6 query, like ip6.arpa.
+	DNSNibbleIPv6Label = "ip6"
+)
+
+// DNSService runs the DNS server on the configured address, over both UDP and TCP.
+// It shuts down both servers on SIGINT or SIGTERM or when one of them fails, then closes the readers.
+func (m *Manager) DNSService() error {
+	packetConn, err := net.ListenPacket("udp", m.Conf.DNSAddr)
+	if err != nil {
+		log.Debug("net.ListenPacket error: ", err)
+		return err
+	}
+	listener, err := net.Listen("tcp", m.Conf.DNSAddr)
+	if err != nil {
+		log.Debug("net.Listen error: ", err)
+		_ = packetConn.Close()
+		return err
+	}
+
+	// Shut down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Reload the databases when they are updated
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := m.WatchDatabases(ctx); err != nil {
+			log.Error("Failed to watch databases:", err)
+		}
+	}()
+
+	log.Infof("DNS server listening on %s, zone %s", m.Conf.DNSAddr, dns.Fqdn(strings.ToLower(m.Conf.DNSZone)))
+	err = m.serveDNSServers(ctx, packetConn, listener)
+
+	// Close the readers after nothing is serving or reloading them
+	cancel()
+	wg.Wait()
+	m.shutdown()
+	return err
+}
+
+// serveDNSServers serves the zone on the UDP and TCP listeners until ctx is done or a server fails,
+// then shuts down both servers, waiting for the queries in flight up to the shutdown timeout.
+func (m *Manager) serveDNSServers(ctx context.Context, packetConn net.PacketConn, listener net.Listener) error {
+	zone := dns.Fqdn(strings.ToLower(m.Conf.DNSZone))
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m.serveDNS(w, r, zone)
+	})
+
+	servers := []*dns.Server{
+		{PacketConn: packetConn, Handler: handler},
+		{Listener: listener, Handler: handler},
+	}
+	errChan := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			errChan <- server.ActivateAndServe()
+		}(server)
+	}
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down DNS server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(m.Conf.ShutdownTimeoutS)*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.ShutdownContext(shutdownCtx); err != nil {
+			log.Debug("server.ShutdownContext error: ", err)
+		}
+	}
+	// a server that has not started is stopped by closing its listener
+	_ = packetConn.Close()
+	_ = listener.Close()
+	return err
+}
+
+// serveDNS answers a query of the zone.
+// The zone apex has no TXT record, so it is answered with its SOA record only.
+// Negative answers carry the SOA record in the authority section for negative caching (RFC 2308).
+func (m *Manager) serveDNS(w dns.ResponseWriter, r *dns.Msg, zone string) {
+	msg := new(dns.Msg)
+	msg.SetReply(r)
+	msg.Authoritative = true
+
+	for _, q := range r.Question {
+		if dns.Fqdn(strings.ToLower(q.Name)) == zone {
+			if q.Qclass == dns.ClassINET && (q.Qtype == dns.TypeSOA || q.Qtype == dns.TypeANY) {
+				msg.Answer = append(msg.Answer, m.dnsSOA(zone))
+			}
+			continue
+		}
+
+		ip, err := ParseDNSName(q.Name, zone)
+		if err != nil {
+			log.Debugf("ParseDNSName %s error: %s", q.Name, err)
+			if err == errors.ErrDNSNameOutOfZone {
+				msg.Rcode = dns.RcodeRefused
+			} else {
+				msg.Rcode = dns.RcodeNameError
+			}
+			break
+		}
+		if q.Qclass != dns.ClassINET || (q.Qtype != dns.TypeTXT && q.Qtype != dns.TypeANY) {
+			continue
+		}
+
+		info, err := m.parseIP(ip.String())
+		if err != nil {
+			log.Debugf("parseIP %s error: %s", ip, err)
+			msg.Rcode = dns.RcodeServerFailure
+			break
+		}
+		msg.Answer = append(msg.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{
+				Name:   q.Name,
+				Rrtype: dns.TypeTXT,
+				Class:  dns.ClassINET,
+				Ttl:    uint32(m.Conf.DNSTTL),
+			},
+			Txt: m.dnsTXT(info),
+		})
+	}
+
+	if len(msg.Answer) == 0 && (msg.Rcode == dns.RcodeSuccess || msg.Rcode == dns.RcodeNameError) {
+		msg.Ns = append(msg.Ns, m.dnsSOA(zone))
+	}
+
+	if err := w.WriteMsg(msg); err != nil {
+		log.Debug("WriteMsg error: ", err)
+	}
+}
+
+// dnsSOA returns the SOA record of the zone. The zone has no name server records,
+// so the primary name server is the zone itself. The TTLs are the configured TTL.
+func (m *Manager) dnsSOA(zone string) *dns.SOA {
+	ttl := uint32(m.Conf.DNSTTL)
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		Ns:      zone,
+		Mbox:    "hostmaster." + zone,
+		Serial:  1,
+		Refresh: ttl,
+		Retry:   ttl,
+		Expire:  ttl,
+		Minttl:  ttl,
+	}
+}
+
+// dnsTXT returns the selected fields of the IP information as "field=value" strings, in field order.
+func (m *Manager) dnsTXT(info *model.IPInfo) []string {
+	fieldAliasReverse := make(map[string]string, len(info.FieldAlias))
+	for commonField, dbField := range info.FieldAlias {
+		fieldAliasReverse[dbField] = commonField
+	}
+
+	values := info.Values()
+	txt := make([]string, 0, len(info.Fields))
+	for i, field := range info.Fields {
+		if commonField, ok := fieldAliasReverse[field]; ok && !m.Conf.UseDBFields {
+			field = commonField
+		}
+		txt = append(txt, truncateTXT(field+"="+values[i]))
+	}
+	return txt
+}
+
+// DNSTXTMaxLength is the maximum length in bytes of a character string of a TXT record.
+const DNSTXTMaxLength = 255
+
+// truncateTXT truncates s to DNSTXTMaxLength bytes, without cutting a multi-byte UTF-8 character.
+func truncateTXT(s string) string {
+	if len(s) <= DNSTXTMaxLength {
+		return s
+	}
+	i := DNSTXTMaxLength
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i]
+}
+
+// ParseDNSName parses the IP address of a query name under the zone.
+// Both names and the zone are case-insensitive and may be fully qualified.
+func ParseDNSName(name, zone string) (net.IP, error) {
+	name, zone = dns.Fqdn(strings.ToLower(name)), dns.Fqdn(strings.ToLower(zone))
+	if name == zone {
+		return nil, errors.ErrInvalidIP
+	}
+	if !strings.HasSuffix(name, "."+zone) {
+		return nil, errors.ErrDNSNameOutOfZone
+	}
+
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, "."+zone))
+	if len(labels) == 0 {
+		return nil, errors.ErrInvalidIP
+	}
+
+	var ip net.IP
+	switch last := labels[len(labels)-1]; {
+	case last == DNSReversedIPv4Label && len(labels) == 5:
+		labels = labels[:4]
+		reverse(labels)
+		ip = net.ParseIP(strings.Join(labels, "."))
+		if ip == nil || ip.To4() == nil {
+			return nil, errors.ErrInvalidIP
+		}
+	case last == DNSNibbleIPv6Label && len(labels) == 33:
+		nibbles := labels[:32]
+		reverse(nibbles)
+		buf := &strings.Builder{}
+		for i, nibble := range nibbles {
+			if len(nibble) != 1 {
+				return nil, errors.ErrInvalidIP
+			}
+			if i > 0 && i%4 == 0 {
+				buf.WriteByte(':')
+			}
+			buf.WriteString(nibble)
+		}
+		ip = net.ParseIP(buf.String())
+	case len(labels) == 4:
+		ip = net.ParseIP(strings.Join(labels, "."))
+		if ip == nil || ip.To4() == nil {
+			return nil, errors.ErrInvalidIP
+		}
+	case len(labels) == 1:
+		ip = net.ParseIP(strings.ReplaceAll(last, "-", ":"))
+	}
+
+	if ip == nil {
+		return nil, errors.ErrInvalidIP
+	}
+	return ip, nil
+}
+
+// reverse reverses the labels in place.
+func reverse(labels []string) {
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestParseDNSName(t *testing.T) {
+	ast := assert.New(t)
+
+	cases := map[string]string{
+		"8.8.8.8.geo.local":          "8.8.8.8",
+		"8.8.4.4.In-Addr.Geo.Local.": "4.4.8.8",
+		"2001-db8--1.geo.local":      "2001:db8::1",
+		"--ffff-1.2.3.4.geo.local":   "",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.geo.local": "2001:db8::1",
+	}
+	for name, expected := range cases {
+		ip, err := ParseDNSName(name, "geo.local")
+		if len(expected) == 0 {
+			ast.Equal(errors.ErrInvalidIP, err, name)
+			continue
+		}
+		ast.Nil(err, name)
+		ast.True(net.ParseIP(expected).Equal(ip), name)
+	}
+
+	for _, name := range []string{"geo.local", "foo.geo.local", "1.2.3.geo.local", "1.2.3.256.geo.local", "1.2.3.4.5.in-addr.geo.local"} {
+		_, err := ParseDNSName(name, "geo.local")
+		ast.Equal(errors.ErrInvalidIP, err, name)
+	}
+
+	_, err := ParseDNSName("8.8.8.8.example.com", "geo.local")
+	ast.Equal(errors.ErrDNSNameOutOfZone, err)
+	_, err = ParseDNSName("8.8.8.8.fakegeo.local", "geo.local")
+	ast.Equal(errors.ErrDNSNameOutOfZone, err)
+}
+
+func TestTruncateTXT(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal("city=深圳", truncateTXT("city=深圳"))
+
+	s := strings.Repeat("a", DNSTXTMaxLength)
+	ast.Equal(s, truncateTXT(s))
+
+	// 3-byte characters after "isp=" end at 253 bytes, the next one is cut out as a whole
+	s = "isp=" + strings.Repeat("电", 100)
+	ret := truncateTXT(s)
+	ast.True(utf8.ValidString(ret))
+	ast.Len(ret, 4+83*3)
+	ast.True(strings.HasPrefix(s, ret))
+}
+
+func TestServeDNSServers(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	m.Conf.DNSZone = "Geo.Local"
+	m.Conf.DNSTTL = 60
+	m.Conf.ShutdownTimeoutS = 5
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- m.serveDNSServers(ctx, packetConn, listener)
+	}()
+
+	exchange := func(network, addr, name string, qtype uint16) *dns.Msg {
+		msg := new(dns.Msg)
+		msg.SetQuestion(name, qtype)
+		client := &dns.Client{Net: network, Timeout: 5 * time.Second}
+		resp, _, err := client.Exchange(msg, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := exchange("udp", packetConn.LocalAddr().String(), "200.1.1.1.geo.local.", dns.TypeTXT)
+	ast.Equal(dns.RcodeSuccess, resp.Rcode)
+	if ast.Len(resp.Answer, 1) {
+		// the unpacked TXT strings escape the non-ASCII bytes
+		ast.True(strings.HasPrefix(resp.Answer[0].(*dns.TXT).Txt[0], "country="))
+	}
+	resp = exchange("tcp", listener.Addr().String(), "200.1.1.1.geo.local.", dns.TypeTXT)
+	ast.Len(resp.Answer, 1)
+
+	// the zone apex has no data, but is not a missing name
+	resp = exchange("udp", packetConn.LocalAddr().String(), "geo.local.", dns.TypeTXT)
+	ast.Equal(dns.RcodeSuccess, resp.Rcode)
+	ast.Empty(resp.Answer)
+	if ast.Len(resp.Ns, 1) {
+		ast.Equal("geo.local.", resp.Ns[0].(*dns.SOA).Hdr.Name)
+	}
+	resp = exchange("udp", packetConn.LocalAddr().String(), "GEO.local.", dns.TypeSOA)
+	ast.Equal(dns.RcodeSuccess, resp.Rcode)
+	ast.Len(resp.Answer, 1)
+
+	resp = exchange("udp", packetConn.LocalAddr().String(), "bogus.geo.local.", dns.TypeTXT)
+	ast.Equal(dns.RcodeNameError, resp.Rcode)
+	ast.Len(resp.Ns, 1)
+
+	// both servers are shut down
+	cancel()
+	select {
+	case err := <-served:
+		ast.Nil(err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the DNS servers did not stop")
+	}
+	_, err = net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	ast.NotNil(err)
+}
@@ -17,13 +17,17 @@
 package ips
 
 import (
-	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/sjzar/ips/pkg/errors"
@@ -38,11 +42,12 @@ IPDB
 City Free (2018-11-18): https://raw.githubusercontent.com/ipipdotnet/ipdb-go/master/city.free.ipdb
 
 CZ88.NET
-qqwry.dat: https://github.com/HMBSbige/qqwry/releases/download/2023-11-01/qqwry.dat
-qqwry.dat Mirror 2: https://github.com/metowolf/qqwry.dat/releases/download/20231025/qqwry.dat
+qqwry.dat: https://github.com/metowolf/qqwry.dat/releases/latest/download/qqwry.dat
+qqwry.dat Mirror 2: https://github.com/HMBSbige/qqwry/releases/latest/download/qqwry.dat
+qqwry.dat Mirror 3 (2023-10-25): https://github.com/metowolf/qqwry.dat/releases/download/20231025/qqwry.dat
 
 MaxMind
-GeoLite2-City.mmdb (2023-10-16): https://github.com/P3TERX/GeoLite.mmdb/releases/latest/download/GeoLite2-City.mmdb (https://git.io/GeoLite2-City.mmdb)
+GeoLite2-City.mmdb: https://github.com/P3TERX/GeoLite.mmdb/releases/latest/download/GeoLite2-City.mmdb (https://git.io/GeoLite2-City.mmdb)
 
 ZX Inc.
 zxipv6wry.db (2021-05-11): https://raw.githubusercontent.com/ZX-Inc/zxipdb-python/main/data/ipv6wry.db
@@ -51,76 +56,449 @@ zxipv6wry.db Mirror 2 (2021-05-11): https://ip.zxinc.org/ip.7z
 ip2region
 ip2region.db (2022-12-07): https://raw.githubusercontent.com/lionsoul2014/ip2region/master/data/ip2region.xdb
 
-DB-IP
-dbip-city-lite.mmdb (2023-11): https://download.db-ip.com/free/dbip-city-lite-2023-11.mmdb.gz
-dbip-asn-lite.mmdb (2023-11): https://download.db-ip.com/free/dbip-asn-lite-2023-11.mmdb.gz
+DB-IP (monthly)
+dbip-city-lite.mmdb: https://download.db-ip.com/free/dbip-city-lite-{2006-01}.mmdb.gz
+dbip-asn-lite.mmdb: https://download.db-ip.com/free/dbip-asn-lite-{2006-01}.mmdb.gz
 
 */
 
-var DownloadMap = map[string]string{
-	"city.free.ipdb":      "https://raw.githubusercontent.com/ipipdotnet/ipdb-go/master/city.free.ipdb",
-	"qqwry.dat":           "https://github.com/metowolf/qqwry.dat/releases/download/20231025/qqwry.dat",
-	"zxipv6wry.db":        "https://raw.githubusercontent.com/ZX-Inc/zxipdb-python/main/data/ipv6wry.db",
-	"GeoLite2-City.mmdb":  "https://git.io/GeoLite2-City.mmdb",
-	"ip2region.xdb":       "https://raw.githubusercontent.com/lionsoul2014/ip2region/master/data/ip2region.xdb",
-	"dbip-city-lite.mmdb": "https://download.db-ip.com/free/dbip-city-lite-2023-11.mmdb.gz",
-	"dbip-asn-lite.mmdb":  "https://download.db-ip.com/free/dbip-asn-lite-2023-11.mmdb.gz",
+// DownloadSource describes where and how a database file is downloaded.
+// Besides the predefined sources in DefaultDownloadSources, sources can be defined
+// by the download_sources option in the config file.
+type DownloadSource struct {
+
+	// Name is the database file name in the ips dir.
+	Name string `mapstructure:"name" json:"name"`
+
+	// URLs lists the download URLs. The first URL is the primary source, the others are mirrors
+	// that are tried in order when it fails. URLs may contain date placeholders, see ResolveURL.
+	URLs []string `mapstructure:"urls" json:"urls"`
+
+	// Headers specifies extra HTTP headers sent with the requests, e.g. a license key.
+	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty"`
+
+	// Username and Password are used for HTTP basic authentication, if set.
+	Username string `mapstructure:"username" json:"username,omitempty"`
+	Password string `mapstructure:"password" json:"password,omitempty"`
+
+	// ChecksumURL is the URL of a sha256 checksum file of the downloaded content.
+	// It may contain the same date placeholders as the URLs.
+	ChecksumURL string `mapstructure:"checksum_url" json:"checksum_url,omitempty"`
+
+	// SHA256 is the pinned sha256 checksum of the downloaded content.
+	SHA256 string `mapstructure:"sha256" json:"sha256,omitempty"`
+
+	// SignatureURL is the URL of a detached signature of the downloaded content.
+	// It may contain the same date placeholders as the URLs.
+	SignatureURL string `mapstructure:"signature_url" json:"signature_url,omitempty"`
+
+	// SignatureType specifies the signature type: minisign or gpg.
+	// It is detected by the SignatureURL suffix if empty, .minisig is minisign and others are gpg.
+	SignatureType string `mapstructure:"signature_type" json:"signature_type,omitempty"`
+
+	// PublicKey is the public key used to verify the signature, either the key itself or the path of a key file.
+	// For gpg, both armored and binary keyrings are supported.
+	PublicKey string `mapstructure:"public_key" json:"public_key,omitempty"`
+
+	// Archive specifies the archive type of the downloaded content: none, gz, tar, tar.gz or zip.
+	// It is detected by the URL suffix if empty.
+	Archive string `mapstructure:"archive" json:"archive,omitempty"`
+
+	// ArchiveFile is the base name of the database file packed in a tar or zip archive. (default is Name)
+	ArchiveFile string `mapstructure:"archive_file" json:"archive_file,omitempty"`
+}
+
+// DefaultDownloadSources lists the predefined database files and their download sources.
+var DefaultDownloadSources = []*DownloadSource{
+	{
+		Name: "city.free.ipdb",
+		URLs: []string{
+			"https://raw.githubusercontent.com/ipipdotnet/ipdb-go/master/city.free.ipdb",
+		},
+	},
+	{
+		Name: "qqwry.dat",
+		URLs: []string{
+			"https://github.com/metowolf/qqwry.dat/releases/latest/download/qqwry.dat",
+			"https://github.com/HMBSbige/qqwry/releases/latest/download/qqwry.dat",
+			"https://github.com/metowolf/qqwry.dat/releases/download/20231025/qqwry.dat",
+		},
+	},
+	{
+		Name: "zxipv6wry.db",
+		URLs: []string{
+			"https://raw.githubusercontent.com/ZX-Inc/zxipdb-python/main/data/ipv6wry.db",
+		},
+	},
+	{
+		Name: "GeoLite2-City.mmdb",
+		URLs: []string{
+			"https://github.com/P3TERX/GeoLite.mmdb/releases/latest/download/GeoLite2-City.mmdb",
+			"https://git.io/GeoLite2-City.mmdb",
+		},
+	},
+	{
+		Name: "ip2region.xdb",
+		URLs: []string{
+			"https://raw.githubusercontent.com/lionsoul2014/ip2region/master/data/ip2region.xdb",
+		},
+	},
+	{
+		Name: "dbip-city-lite.mmdb",
+		URLs: []string{
+			"https://download.db-ip.com/free/dbip-city-lite-{2006-01}.mmdb.gz",
+		},
+	},
+	{
+		Name: "dbip-asn-lite.mmdb",
+		URLs: []string{
+			"https://download.db-ip.com/free/dbip-asn-lite-{2006-01}.mmdb.gz",
+		},
+	},
+}
+
+// DownloadSources returns all known download sources sorted by name.
+// Sources defined in the config override the predefined sources with the same name.
+func (m *Manager) DownloadSources() []*DownloadSource {
+	sources := make(map[string]*DownloadSource, len(DefaultDownloadSources)+len(m.Conf.DownloadSources))
+	for _, source := range DefaultDownloadSources {
+		sources[source.Name] = source
+	}
+	for _, source := range m.Conf.DownloadSources {
+		if source == nil || len(source.Name) == 0 {
+			continue
+		}
+		sources[source.Name] = source
+	}
+
+	ret := make([]*DownloadSource, 0, len(sources))
+	for _, source := range sources {
+		ret = append(ret, source)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret
+}
+
+// DownloadSource returns the download source of the database file.
+func (m *Manager) DownloadSource(file string) (*DownloadSource, bool) {
+	for _, source := range m.DownloadSources() {
+		if source.Name == file {
+			return source, true
+		}
+	}
+	return nil, false
 }
 
 // Download downloads the database file to ips dir.
+// If _url is empty, the file is downloaded from its known download source.
 func (m *Manager) Download(file, _url string) error {
 
+	source := &DownloadSource{Name: file, URLs: []string{_url}}
 	if len(_url) == 0 {
-		_url = DownloadMap[file]
-		if len(_url) == 0 {
+		var ok bool
+		if source, ok = m.DownloadSource(file); !ok || len(source.URLs) == 0 {
 			log.Debugf("unknown file %s", file)
 			return errors.ErrFileNotFound
 		}
 	}
 
-	fmt.Printf("Downloading %s from %s to %s\n", file, _url, m.Conf.IPSDir)
+	if _, err := m.downloadFile(source, false); err != nil {
+		return err
+	}
+
+	return nil
+}
 
-	f, err := os.Create(m.Conf.IPSDir + "/" + file)
+// downloadFile downloads the file from the first available url of the source and records it in the manifest.
+// Each url is resolved by ResolveURL, and the next url is tried when all of its candidates fail.
+// If conditional is true and the file has been downloaded before, conditional requests are used
+// and an unchanged file is not downloaded again. It reports whether the file was downloaded.
+func (m *Manager) downloadFile(source *DownloadSource, conditional bool) (bool, error) {
+
+	file := source.Name
+	var prev *ManifestEntry
+	if conditional {
+		manifest, err := LoadManifest(m.Conf.IPSDir)
+		if err != nil {
+			log.Debug("LoadManifest error: ", err)
+			return false, err
+		}
+		prev = manifest.Files[file]
+		if prev != nil && !m.isInstalled(prev) {
+			prev = nil
+		}
+	}
+
+	err := errors.ErrFailedDownload
+	for _, _url := range source.URLs {
+		for _, date := range ResolveDates(_url, time.Now()) {
+			var entry *ManifestEntry
+			entry, err = m.fetch(source, FormatURL(_url, date), date, prev)
+			if err == errors.ErrSourceNotFound {
+				// try an older release of a date template
+				continue
+			}
+			if err != nil {
+				// try the next mirror
+				break
+			}
+			if entry == nil {
+				fmt.Printf("%s is up to date (%s).\n", file, prev.URL)
+				return false, nil
+			}
+			if err := updateManifest(m.Conf.IPSDir, entry); err != nil {
+				log.Debug("updateManifest error: ", err)
+				return false, err
+			}
+			fmt.Println("Download " + file + " success.")
+			return true, nil
+		}
+	}
+
+	log.Debugf("download %s failed: %s", file, err)
+	return false, err
+}
+
+// isInstalled checks whether the file recorded in the manifest entry still exists unmodified in the ips dir.
+func (m *Manager) isInstalled(entry *ManifestEntry) bool {
+	sum, err := util.FileSHA256(filepath.Join(m.Conf.IPSDir, entry.File))
 	if err != nil {
-		log.Debugf("create file %s failed: %s", file, err)
-		return err
+		return false
+	}
+	return sum == entry.SHA256
+}
+
+// newRequest creates a GET request to _url with the headers and credentials of the source.
+func (s *DownloadSource) newRequest(_url string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, _url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range s.Headers {
+		req.Header.Set(key, value)
+	}
+	if len(s.Username) != 0 || len(s.Password) != 0 {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+	return req, nil
+}
+
+// fetchChecksum downloads the checksum file of the source for the given date,
+// and returns the sha256 checksum of the file named name.
+func (s *DownloadSource) fetchChecksum(date time.Time, name string) (string, error) {
+	data, err := s.get(FormatURL(s.ChecksumURL, date))
+	if err != nil {
+		return "", err
+	}
+
+	return ParseChecksum(data, name)
+}
+
+// get downloads a small file of the source, such as a checksum or signature file.
+func (s *DownloadSource) get(_url string) ([]byte, error) {
+	req, err := s.newRequest(_url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Debugf("http get %s failed: %s", _url, err)
+		return nil, err
 	}
 	defer func() {
-		_ = f.Close()
+		_ = resp.Body.Close()
 	}()
 
-	resp, err := http.DefaultClient.Get(_url)
+	if resp.StatusCode != http.StatusOK {
+		log.Debugf("http get %s failed: %s", _url, resp.Status)
+		return nil, errors.ErrFailedDownload
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// ParseChecksum finds the sha256 checksum of the file named name in the content of a checksum file.
+// Both the sha256sum format "<checksum>  <file>" and a bare checksum are supported.
+// If no file matches, a bare checksum or the only checksum in the file is returned.
+func ParseChecksum(data []byte, name string) (string, error) {
+	sums := make([]string, 0, 1)
+	bare := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil {
+			continue
+		}
+		sum := strings.ToLower(fields[0])
+		sums = append(sums, sum)
+		if len(fields) == 1 {
+			if len(bare) == 0 {
+				bare = sum
+			}
+			continue
+		}
+		if path.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return sum, nil
+		}
+	}
+
+	switch {
+	case len(bare) != 0:
+		return bare, nil
+	case len(sums) == 1:
+		return sums[0], nil
+	}
+	return "", errors.ErrInvalidChecksum
+}
+
+// fetch downloads the file of the source from _url to the ips dir.
+// date is the resolved date of _url, used for the checksum url.
+// If prev was downloaded from the same url, a conditional request is sent, and a nil entry
+// is returned when the file has not been modified.
+func (m *Manager) fetch(source *DownloadSource, _url string, date time.Time, prev *ManifestEntry) (*ManifestEntry, error) {
+
+	file := source.Name
+	req, err := source.newRequest(_url)
+	if err != nil {
+		log.Debugf("http.NewRequest %s failed: %s", _url, err)
+		return nil, err
+	}
+	if prev != nil && prev.URL == _url {
+		if len(prev.ETag) != 0 {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if len(prev.LastModified) != 0 {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+
+	fmt.Printf("Downloading %s from %s to %s\n", file, _url, m.Conf.IPSDir)
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Debugf("http get %s failed: %s", _url, err)
-		return err
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if prev != nil && prev.URL == _url {
+			return nil, nil
+		}
+		log.Debugf("http get %s failed: unexpected %s", _url, resp.Status)
+		return nil, errors.ErrFailedDownload
+	case http.StatusNotFound:
+		log.Debugf("http get %s failed: %s", _url, resp.Status)
+		return nil, errors.ErrSourceNotFound
+	default:
 		log.Debugf("http get %s failed: %s", _url, resp.Status)
-		return errors.ErrFailedDownload
+		return nil, errors.ErrFailedDownload
 	}
 
-	var r io.Reader = resp.Body
-	if u, _ := url.Parse(_url); filepath.Ext(u.Path) == ".gz" {
-		if r, err = gzip.NewReader(resp.Body); err != nil {
-			log.Debugf("gzip.NewReader failed: %s", err)
-			return err
+	u, err := url.Parse(_url)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := ""
+	if len(source.ChecksumURL) != 0 {
+		if checksum, err = source.fetchChecksum(date, path.Base(u.Path)); err != nil {
+			log.Debugf("fetch checksum of %s failed: %s", _url, err)
+			return nil, err
 		}
 	}
 
+	verifier, err := source.newSignatureVerifier(date)
+	if err != nil {
+		log.Debugf("fetch signature of %s failed: %s", _url, err)
+		return nil, err
+	}
+	defer verifier.Close()
+
+	// The checksum and signature cover the downloaded content, not the extracted database file.
+	rawHash := sha256.New()
 	bar := util.ProgressBar(
 		resp.ContentLength,
 		"downloading",
 	)
-	_, err = io.Copy(io.MultiWriter(f, bar), r)
+	raw := io.TeeReader(resp.Body, io.MultiWriter(rawHash, verifier, bar))
+
+	archive := source.Archive
+	if len(archive) == 0 {
+		archive = util.ArchiveType(u.Path)
+	}
+	archiveFile := source.ArchiveFile
+	if len(archiveFile) == 0 {
+		archiveFile = file
+	}
+	r, err := util.OpenArchive(raw, archive, archiveFile)
 	if err != nil {
+		log.Debugf("util.OpenArchive %s failed: %s", _url, err)
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	// Download to a temporary file, the previous file is kept until the new one is validated.
+	f, err := util.CreateAtomicFile(filepath.Join(m.Conf.IPSDir, file))
+	if err != nil {
+		log.Debugf("create file %s failed: %s", file, err)
+		return nil, err
+	}
+	defer func() {
+		_ = f.Abort()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), r); err != nil {
 		log.Debugf("io.Copy failed: %s", err)
-		return err
+		return nil, err
 	}
 
-	fmt.Println("Download " + file + " success.")
-	return nil
+	// drain the rest of the archive, so the whole content is hashed
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		log.Debugf("io.Copy failed: %s", err)
+		return nil, err
+	}
+
+	// Verification failures abort the temporary file, the previous file is kept.
+	sum := hex.EncodeToString(rawHash.Sum(nil))
+	for _, expected := range []string{checksum, strings.ToLower(source.SHA256)} {
+		if len(expected) != 0 && sum != expected {
+			log.Debugf("checksum of %s mismatch, expected %s, got %s", _url, expected, sum)
+			return nil, errors.ErrChecksumMismatch
+		}
+	}
+
+	if err := verifier.Verify(); err != nil {
+		log.Debugf("verify signature of %s failed: %s", _url, err)
+		return nil, err
+	}
+
+	if err := f.Commit(func(name string) error {
+		return validateDatabase("", name)
+	}); err != nil {
+		log.Debugf("commit file %s failed: %s", file, err)
+		return nil, err
+	}
+
+	return &ManifestEntry{
+		File:         file,
+		URL:          _url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		DownloadTime: time.Now(),
+	}, nil
 }
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestParseChecksum(t *testing.T) {
+	ast := assert.New(t)
+
+	sum1 := "3b86ebf60fad4024f5659e8eec1a7aa4164c9bf64b797ee66461ffcc28c3f618"
+	sum2 := "B5BB9D8014A0F9B1D61E21E796D78DCCDF1352F23CD32812F4850B878AE4944C"
+
+	ret, err := ParseChecksum([]byte(sum1+"\n"), "city.tar.gz")
+	ast.Nil(err)
+	ast.Equal(sum1, ret)
+
+	ret, err = ParseChecksum([]byte(sum1+"  asn.tar.gz\n"+sum2+" *dir/city.tar.gz\n"), "city.tar.gz")
+	ast.Nil(err)
+	ast.Equal("b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c", ret)
+
+	ret, err = ParseChecksum([]byte(sum1+"  GeoLite2-City_20231017.tar.gz\n"), "geoip_download")
+	ast.Nil(err)
+	ast.Equal(sum1, ret)
+
+	_, err = ParseChecksum([]byte(sum1+"  asn.tar.gz\n"+sum1+"  city.tar.gz\n"), "geoip_download")
+	ast.Equal(errors.ErrInvalidChecksum, err)
+
+	_, err = ParseChecksum([]byte("<html>not found</html>"), "city.tar.gz")
+	ast.Equal(errors.ErrInvalidChecksum, err)
+}
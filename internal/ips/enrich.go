@@ -0,0 +1,396 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+const (
+	// EnrichChunkSize is the number of lines processed by a worker at a time.
+	EnrichChunkSize = 1000
+
+	// EnrichMaxLineSize is the maximum length of an input line.
+	EnrichMaxLineSize = 1024 * 1024
+
+	// EnrichEmptyValue is written for the empty values, so that the columns are kept.
+	EnrichEmptyValue = "-"
+)
+
+// EnrichOption specifies how the IP of each line is extracted and how the fields are appended.
+type EnrichOption struct {
+	// Workers specifies the number of concurrent workers. It is the number of CPUs if not positive.
+	Workers int
+
+	// Column specifies the 1-based column of the IP, split by Delimiter.
+	// The first IP in the line is used if not positive.
+	Column int
+
+	// Delimiter specifies the delimiter of the columns. The columns are split by white spaces if empty.
+	Delimiter string
+
+	// Regexp specifies the pattern extracting the IP, by the "ip" named group, the first group or the whole match.
+	// It takes precedence over Column.
+	Regexp string
+
+	// Separator specifies the separator of the appended fields. (default is tab)
+	Separator string
+}
+
+// EnrichStats describes the progress of an enrichment.
+type EnrichStats struct {
+	Lines    int64         // Number of lines written.
+	Bytes    int64         // Number of bytes read.
+	IPs      int64         // Number of IPs looked up.
+	Failures int64         // Number of lines without an IP, or whose IP failed to look up.
+	Elapsed  time.Duration // Time elapsed.
+}
+
+// LinesPerSecond returns the throughput of the enrichment.
+func (s *EnrichStats) LinesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Lines) / s.Elapsed.Seconds()
+}
+
+// enrichChunk is a chunk of lines, done when its output is ready.
+type enrichChunk struct {
+	lines []string
+	out   []byte
+	done  chan struct{}
+}
+
+// EnrichFile enriches the lines of the input file into the output file, and logs the throughput.
+// The input is the standard input if it is empty or "-", and the output is the standard output if it is empty.
+// The output file is written atomically, and a progress bar is shown while writing it.
+func (m *Manager) EnrichFile(inputFile, outputFile string, option EnrichOption) error {
+	enricher, err := m.NewEnricher(option)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	size := int64(-1)
+	if len(inputFile) != 0 && inputFile != "-" {
+		f, err := os.Open(inputFile)
+		if err != nil {
+			log.Debug("os.Open error: ", err)
+			return err
+		}
+		defer f.Close()
+		if stat, err := f.Stat(); err == nil {
+			size = stat.Size()
+		}
+		input = f
+	}
+
+	output := os.Stdout
+	var atomicFile *util.AtomicFile
+	if len(outputFile) != 0 {
+		var err error
+		atomicFile, err = util.CreateAtomicFile(outputFile)
+		if err != nil {
+			log.Debug("util.CreateAtomicFile error: ", err)
+			return err
+		}
+		defer func() {
+			_ = atomicFile.Abort()
+		}()
+		output = atomicFile.File
+
+		bar := util.ProgressBar(size, "enriching")
+		defer func() {
+			_ = bar.Finish()
+		}()
+		input = io.TeeReader(input, bar)
+	}
+
+	if err := enricher.Enrich(input, output); err != nil {
+		return err
+	}
+	if atomicFile != nil {
+		if err := atomicFile.Commit(nil); err != nil {
+			log.Debug("atomicFile.Commit error: ", err)
+			return err
+		}
+	}
+
+	stats := enricher.Stats()
+	log.Infof("enriched %d lines, %d IPs, %d failures in %s, %.0f lines/s, %.2f MB/s",
+		stats.Lines, stats.IPs, stats.Failures, stats.Elapsed.Round(time.Millisecond),
+		stats.LinesPerSecond(), float64(stats.Bytes)/1024/1024/stats.Elapsed.Seconds())
+	return nil
+}
+
+// Enricher appends the fields of the IP of each line as new columns, keeping the lines in order.
+type Enricher struct {
+	manager *Manager
+	option  EnrichOption
+	pattern *regexp.Regexp
+	group   int
+	columns map[string]int
+	empty   []string
+
+	lines    atomic.Int64
+	bytes    atomic.Int64
+	ips      atomic.Int64
+	failures atomic.Int64
+	start    time.Time
+}
+
+// NewEnricher creates an Enricher of the option.
+func (m *Manager) NewEnricher(option EnrichOption) (*Enricher, error) {
+	if option.Workers <= 0 {
+		option.Workers = runtime.NumCPU()
+	}
+	if len(option.Separator) == 0 {
+		option.Separator = "\t"
+	}
+
+	e := &Enricher{
+		manager: m,
+		option:  option,
+	}
+	if len(option.Regexp) != 0 {
+		pattern, err := regexp.Compile(option.Regexp)
+		if err != nil {
+			log.Debug("regexp.Compile error: ", err)
+			return nil, errors.ErrInvalidRegexp
+		}
+		e.pattern = pattern
+		if e.group = pattern.SubexpIndex("ip"); e.group < 0 {
+			e.group = 0
+			if pattern.NumSubexp() > 0 {
+				e.group = 1
+			}
+		}
+	}
+
+	fields, err := m.outputFields()
+	if err != nil {
+		return nil, err
+	}
+	e.columns = make(map[string]int, len(fields))
+	for i, field := range fields {
+		e.columns[field] = i
+	}
+	e.empty = make([]string, len(fields))
+	for i := range e.empty {
+		e.empty[i] = EnrichEmptyValue
+	}
+
+	return e, nil
+}
+
+// Enrich reads the lines of r, and writes them with the fields appended to w in order.
+// The lines are processed by the workers in chunks.
+func (e *Enricher) Enrich(r io.Reader, w io.Writer) error {
+	e.start = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan *enrichChunk, e.option.Workers)
+	order := make(chan *enrichChunk, e.option.Workers*2)
+	wg := sync.WaitGroup{}
+	for i := 0; i < e.option.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				chunk.out = e.enrichChunk(chunk.lines)
+				close(chunk.done)
+			}
+		}()
+	}
+	defer wg.Wait()
+
+	var readErr error
+	go func() {
+		defer close(order)
+		defer close(jobs)
+		readErr = e.read(ctx, r, jobs, order)
+	}()
+
+	bw := bufio.NewWriter(w)
+	for chunk := range order {
+		<-chunk.done
+		if _, err := bw.Write(chunk.out); err != nil {
+			log.Debug("bufio.Writer.Write error: ", err)
+			cancel()
+			for range order {
+			}
+			return err
+		}
+		e.lines.Add(int64(len(chunk.lines)))
+	}
+	if err := bw.Flush(); err != nil {
+		log.Debug("bufio.Writer.Flush error: ", err)
+		return err
+	}
+	return readErr
+}
+
+// Stats returns the progress of the enrichment.
+func (e *Enricher) Stats() *EnrichStats {
+	return &EnrichStats{
+		Lines:    e.lines.Load(),
+		Bytes:    e.bytes.Load(),
+		IPs:      e.ips.Load(),
+		Failures: e.failures.Load(),
+		Elapsed:  time.Since(e.start),
+	}
+}
+
+// read splits the lines of r into chunks, and sends each chunk to both the workers and the writer.
+func (e *Enricher) read(ctx context.Context, r io.Reader, jobs, order chan<- *enrichChunk) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), EnrichMaxLineSize)
+
+	send := func(lines []string) bool {
+		chunk := &enrichChunk{lines: lines, done: make(chan struct{})}
+		select {
+		case order <- chunk:
+		case <-ctx.Done():
+			return false
+		}
+		select {
+		case jobs <- chunk:
+		case <-ctx.Done():
+			return false
+		}
+		return true
+	}
+
+	lines := make([]string, 0, EnrichChunkSize)
+	for scanner.Scan() {
+		e.bytes.Add(int64(len(scanner.Bytes()) + 1))
+		lines = append(lines, scanner.Text())
+		if len(lines) == EnrichChunkSize {
+			if !send(lines) {
+				return nil
+			}
+			lines = make([]string, 0, EnrichChunkSize)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Debug("bufio.Scanner error: ", err)
+		return err
+	}
+	if len(lines) > 0 {
+		send(lines)
+	}
+	return nil
+}
+
+// enrichChunk returns the lines of the chunk with the fields appended.
+func (e *Enricher) enrichChunk(lines []string) []byte {
+	buf := make([]byte, 0, len(lines)*256)
+	for _, line := range lines {
+		buf = append(buf, line...)
+		for _, value := range e.enrichLine(line) {
+			buf = append(buf, e.option.Separator...)
+			buf = append(buf, value...)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// enrichLine returns the field values of the IP of the line.
+func (e *Enricher) enrichLine(line string) []string {
+	ip := e.extractIP(line)
+	if len(ip) == 0 {
+		e.failures.Add(1)
+		return e.empty
+	}
+
+	e.ips.Add(1)
+	info, err := e.manager.parseIP(ip)
+	if err != nil {
+		log.Debug("parseIP error: ", err)
+		e.failures.Add(1)
+		return e.empty
+	}
+
+	// the fields are placed by name, as the IPv4 and IPv6 readers may select different fields
+	values := append([]string(nil), e.empty...)
+	infoValues := info.Values()
+	for i, field := range info.OutputFields(e.manager.Conf.UseDBFields) {
+		if j, ok := e.columns[field]; ok && len(infoValues[i]) != 0 {
+			values[j] = infoValues[i]
+		}
+	}
+	return values
+}
+
+// extractIP returns the IP of the line, or an empty string if not found.
+func (e *Enricher) extractIP(line string) string {
+	switch {
+	case e.pattern != nil:
+		match := e.pattern.FindStringSubmatch(line)
+		if len(match) <= e.group {
+			return ""
+		}
+		return validIP(match[e.group])
+	case e.option.Column > 0:
+		var columns []string
+		if len(e.option.Delimiter) == 0 {
+			columns = strings.Fields(line)
+		} else {
+			columns = strings.Split(line, e.option.Delimiter)
+		}
+		if len(columns) < e.option.Column {
+			return ""
+		}
+		return validIP(columns[e.option.Column-1])
+	default:
+		for _, segment := range parser.NewTextParser(line).Parse().Segments {
+			if segment.Type == parser.TextTypeIPv4 || segment.Type == parser.TextTypeIPv6 {
+				return segment.Content
+			}
+		}
+		return ""
+	}
+}
+
+// validIP returns the IP of the column, with the surrounding quotes, brackets and port removed,
+// or an empty string if it is not an IP.
+func validIP(column string) string {
+	column = strings.Trim(strings.TrimSpace(column), `"'`)
+	ip, err := parseHostAddr(column)
+	if err != nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPlainDB is an IPv4 database in the plain format.
+const testPlainDB = `# Meta: {"MetaVersion": 1, "Format": "plain", "IPVersion": 1, "Fields": ["country", "province", "city", "isp"], "FieldAlias": {}}
+0.0.0.0/1	保留,,,
+128.0.0.0/2	美国,,,
+192.0.0.0/3	中国,广东,深圳,电信
+224.0.0.0/3	中国,广东,广州,
+`
+
+// newTestManager creates a Manager of the plain test database.
+func newTestManager(t *testing.T, fields string) *Manager {
+	file := filepath.Join(t.TempDir(), "ipv4.txt")
+	if err := os.WriteFile(file, []byte(testPlainDB), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(&Config{
+		IPv4File:   []string{file},
+		IPv4Format: []string{"plain"},
+		Fields:     fields,
+	})
+}
+
+func TestEnrich(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city,isp")
+
+	input := &bytes.Buffer{}
+	expected := &bytes.Buffer{}
+	for i := 0; i < EnrichChunkSize*3+10; i++ {
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(input, "200.1.1.%d - GET /%d\n", i%250, i)
+			fmt.Fprintf(expected, "200.1.1.%d - GET /%d\t中国\t深圳\t电信\n", i%250, i)
+		case 1:
+			fmt.Fprintf(input, "\"230.0.0.1\" - GET /%d\n", i)
+			fmt.Fprintf(expected, "\"230.0.0.1\" - GET /%d\t中国\t广州\t-\n", i)
+		default:
+			fmt.Fprintf(input, "- - GET /%d\n", i)
+			fmt.Fprintf(expected, "- - GET /%d\t-\t-\t-\n", i)
+		}
+	}
+
+	e, err := m.NewEnricher(EnrichOption{Workers: 4, Column: 1})
+	ast.Nil(err)
+	output := &bytes.Buffer{}
+	ast.Nil(e.Enrich(input, output))
+	ast.Equal(expected.String(), output.String())
+
+	stats := e.Stats()
+	ast.Equal(int64(EnrichChunkSize*3+10), stats.Lines)
+	ast.Equal(int64((EnrichChunkSize*3+10)/3), stats.Failures)
+}
+
+func TestEnrichFields(t *testing.T) {
+	ast := assert.New(t)
+
+	// the IPv6 database has other fields than the IPv4 one
+	m := newTestManager(t, "")
+	file := filepath.Join(t.TempDir(), "ipv6.txt")
+	db := `# Meta: {"MetaVersion": 1, "Format": "plain", "IPVersion": 2, "Fields": ["country", "asn"], "FieldAlias": {}}
+::/1	保留,
+8000::/1	德国,AS3320
+`
+	if err := os.WriteFile(file, []byte(db), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.Conf.IPv6File = []string{file}
+	m.Conf.IPv6Format = []string{"plain"}
+
+	fields, err := m.outputFields()
+	ast.Nil(err)
+	ast.Equal([]string{"country", "province", "city", "isp", "asn"}, fields)
+
+	e, err := m.NewEnricher(EnrichOption{Workers: 1, Column: 1})
+	ast.Nil(err)
+	output := &bytes.Buffer{}
+	ast.Nil(e.Enrich(strings.NewReader("200.1.1.1\n8000::1\n-\n"), output))
+	ast.Equal("200.1.1.1\t中国\t广东\t深圳\t电信\t-\n8000::1\t德国\t-\t-\t-\tAS3320\n-\t-\t-\t-\t-\t-\n", output.String())
+}
+
+func TestExtractIP(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+
+	e, err := m.NewEnricher(EnrichOption{})
+	ast.Nil(err)
+	ast.Equal("1.1.1.1", e.extractIP("from 1.1.1.1 to 8.8.8.8"))
+	ast.Equal("", e.extractIP("no ip"))
+
+	e, err = m.NewEnricher(EnrichOption{Column: 2, Delimiter: ","})
+	ast.Nil(err)
+	ast.Equal("8.8.8.8", e.extractIP("1.1.1.1,8.8.8.8:53"))
+	ast.Equal("2001:db8::1", e.extractIP("a,[2001:db8::1]:443"))
+	ast.Equal("", e.extractIP("1.1.1.1"))
+
+	e, err = m.NewEnricher(EnrichOption{Regexp: `to=(?P<ip>\S+)`})
+	ast.Nil(err)
+	ast.Equal("8.8.8.8", e.extractIP("from=1.1.1.1 to=8.8.8.8"))
+	ast.Equal("", e.extractIP("to=unknown"))
+
+	_, err = m.NewEnricher(EnrichOption{Regexp: `(`})
+	ast.NotNil(err)
+
+	e, err = m.NewEnricher(EnrichOption{})
+	ast.Nil(err)
+	ast.Equal([]string{"中国"}, e.enrichLine(strings.Repeat(" ", 3)+"200.1.1.1"))
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/sjzar/ips/domainlist"
 	"github.com/sjzar/ips/format"
 	"github.com/sjzar/ips/format/czdb"
+	"github.com/sjzar/ips/format/geo"
 	"github.com/sjzar/ips/format/mmdb"
 	"github.com/sjzar/ips/format/qqwry"
 	"github.com/sjzar/ips/internal/data"
@@ -56,6 +57,11 @@ func (m *Manager) ParseText(text string) (string, error) {
 		infoList = append(infoList, info)
 	}
 
+	if m.stats != nil {
+		m.addStats(infoList)
+		return "", nil
+	}
+
 	result, err := m.serialize(infoList)
 	if err != nil {
 		log.Debug("m.serialize error: ", err)
@@ -95,30 +101,57 @@ func (m *Manager) parseIP(content string) (*model.IPInfo, error) {
 func (m *Manager) parseIPv4(ip net.IP) (*model.IPInfo, error) {
 
 	// lazyLoad initializes the IP readers if they haven't been initialized yet.
-	if m.ipv4 == nil {
-		var err error
-		if m.ipv4, err = m.createReader(m.Conf.IPv4Format, m.Conf.IPv4File, false); err != nil {
-			log.Debug("createReader error: ", err)
-			return nil, err
-		}
+	reader, err := m.ipv4.acquire(m.loadIPv4Reader)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.release()
 
-	return m.ipv4.Find(ip)
+	info, err := reader.Find(ip)
+	observeLookup(reader, err)
+	return info, err
 }
 
 // parseIPv6 finds and returns the information associated with the provided IPv6 address.
 func (m *Manager) parseIPv6(ip net.IP) (*model.IPInfo, error) {
 
 	// lazyLoad initializes the IP readers if they haven't been initialized yet.
-	if m.ipv6 == nil {
-		var err error
-		if m.ipv6, err = m.createReader(m.Conf.IPv6Format, m.Conf.IPv6File, false); err != nil {
-			log.Debug("createReader error: ", err)
+	reader, err := m.ipv6.acquire(m.loadIPv6Reader)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.release()
+
+	info, err := reader.Find(ip)
+	observeLookup(reader, err)
+	return info, err
+}
+
+// outputFields returns the output fields of the lookups, the selected fields of the IPv4 reader
+// followed by the other selected fields of the IPv6 reader, loading the configured readers if necessary.
+func (m *Manager) outputFields() ([]string, error) {
+	ret := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, slot := range m.readerSlots() {
+		if !slot.configured {
+			continue
+		}
+		reader, err := slot.holder.acquire(slot.load)
+		if err != nil {
 			return nil, err
 		}
+		meta := reader.Meta()
+		info := &model.IPInfo{Fields: meta.Fields, FieldAlias: meta.FieldAlias}
+		reader.release()
+
+		for _, field := range info.OutputFields(m.Conf.UseDBFields) {
+			if !seen[field] {
+				seen[field] = true
+				ret = append(ret, field)
+			}
+		}
 	}
-
-	return m.ipv6.Find(ip)
+	return ret, nil
 }
 
 // parseDomain fetches the information for the given domain. Implementation is pending.
@@ -163,6 +196,17 @@ func (m *Manager) serialize(data []interface{}) (string, error) {
 		}
 		list.AddAlfredItemEmpty()
 		return m.serializeDataToJSON(list)
+	case OutputTypeCSV:
+		return m.serializeDelimited(data, ',')
+	case OutputTypeTSV:
+		return m.serializeDelimited(data, '\t')
+	case OutputTypeNDJSON:
+		return m.serializeNDJSON(data)
+	case OutputTypeYAML:
+		return m.serializeYAML(data)
+	case OutputTypeTable:
+		m.appendTableRows(data)
+		return "", nil
 	default:
 		// default is OutputTypeText
 		buf := &bytes.Buffer{}
@@ -190,7 +234,15 @@ func (m *Manager) serialize(data []interface{}) (string, error) {
 
 // serializeIPInfoToText takes an IPInfo, then serializes
 // the IPInfo to a text format based on the Manager configuration.
+// The text template is used instead of the text format if configured.
 func (m *Manager) serializeIPInfoToText(ipInfo *model.IPInfo) (string, error) {
+	if tmpl, err := m.textTemplate(); err != nil || tmpl != nil {
+		if err != nil {
+			return "", err
+		}
+		return executeTemplate(tmpl, m.ipInfoTemplateData(ipInfo))
+	}
+
 	values := strings.Join(util.DeleteEmptyValue(ipInfo.Values()), m.Conf.TextValuesSep)
 	if values != "" {
 		ret := strings.Replace(m.Conf.TextFormat, "%origin", ipInfo.IP.String(), 1)
@@ -203,7 +255,15 @@ func (m *Manager) serializeIPInfoToText(ipInfo *model.IPInfo) (string, error) {
 
 // serializeDomainInfoToText takes a DomainInfo, then serializes
 // the DomainInfo to a text format based on the Manager configuration.
+// The text template is used instead of the text format if configured.
 func (m *Manager) serializeDomainInfoToText(domainInfo *model.DomainInfo) (string, error) {
+	if tmpl, err := m.textTemplate(); err != nil || tmpl != nil {
+		if err != nil {
+			return "", err
+		}
+		return executeTemplate(tmpl, m.domainInfoTemplateData(domainInfo))
+	}
+
 	values := strings.Join(util.DeleteEmptyValue(domainInfo.Values()), m.Conf.TextValuesSep)
 	if values != "" {
 		ret := strings.Replace(m.Conf.TextFormat, "%origin", domainInfo.Domain, 1)
@@ -287,9 +347,8 @@ func (m *Manager) createHybridReader(_format, file []string, isPackMode bool) (f
 	reader.OperateChain.Use(rw.Do)
 
 	if len(m.Conf.Lang) != 0 {
-		tl, err := operate.NewTranslator(m.Conf.Lang)
+		tl, err := m.newTranslator()
 		if err != nil {
-			log.Debug("operate.NewTranslator error: ", err)
 			return nil, err
 		}
 		reader.OperateChain.Use(tl.Do)
@@ -330,9 +389,8 @@ func (m *Manager) createStandardReader(_format, file string, isPackMode bool) (f
 	reader.OperateChain.Use(rw.Do)
 
 	if len(m.Conf.Lang) != 0 {
-		tl, err := operate.NewTranslator(m.Conf.Lang)
+		tl, err := m.newTranslator()
 		if err != nil {
-			log.Debug("operate.NewTranslator error: ", err)
 			return nil, err
 		}
 		reader.OperateChain.Use(tl.Do)
@@ -344,21 +402,18 @@ func (m *Manager) createStandardReader(_format, file string, isPackMode bool) (f
 // createDatabaseReader initializes a database reader for the given format and file.
 // It checks for file existence and downloads the database file if necessary.
 func (m *Manager) createDatabaseReader(_format, file string) (format.Reader, error) {
-	if !util.IsFileExist(file) {
-		fullpath := filepath.Join(m.Conf.IPSDir, file)
-		if !util.IsFileExist(fullpath) {
-			// init database file
-			_, ok := DownloadMap[file]
-			if !ok {
-				log.Debugf("file not found %s", file)
-				return nil, errors.ErrFileNotFound
-			}
-			if err := m.Download(file, ""); err != nil {
-				return nil, err
-			}
+	path := m.databasePath(file)
+	if !util.IsFileExist(path) {
+		// init database file
+		if _, ok := m.DownloadSource(file); !ok {
+			log.Debugf("file not found %s", file)
+			return nil, errors.ErrFileNotFound
+		}
+		if err := m.Download(file, ""); err != nil {
+			return nil, err
 		}
-		file = fullpath
 	}
+	file = path
 
 	dbr, err := format.NewReader(_format, file)
 	if err != nil {
@@ -399,6 +454,28 @@ func (m *Manager) createDatabaseReader(_format, file string) (format.Reader, err
 	return dbr, nil
 }
 
+// databasePath returns the path of the database file. Files that do not exist are looked up in the ips dir.
+func (m *Manager) databasePath(file string) string {
+	if util.IsFileExist(file) {
+		return file
+	}
+	return filepath.Join(m.Conf.IPSDir, file)
+}
+
+// validateDatabase checks that the file can be opened as a database of the given format.
+// Files whose format can not be detected are not validated.
+func validateDatabase(_format, file string) error {
+	dbr, err := format.NewReader(_format, file)
+	if err != nil {
+		if err == errors.ErrUnsupportedFormat {
+			return nil
+		}
+		log.Debug("format.NewReader error: ", _format, file, err)
+		return errors.ErrInvalidDatabase
+	}
+	return dbr.Close()
+}
+
 // newFieldSelector initializes a FieldSelector based on the provided metadata and the pack mode configuration.
 // It selects different sets of fields based on whether the pack mode is enabled or not.
 func (m *Manager) newFieldSelector(meta *model.Meta, isPackMode bool) (*operate.FieldSelector, error) {
@@ -414,6 +491,25 @@ func (m *Manager) newFieldSelector(meta *model.Meta, isPackMode bool) (*operate.
 	return fs, nil
 }
 
+// newTranslator creates a Translator of the configured language.
+// The Managers of the profiles keep the global language unchanged, as they share it with the service.
+func (m *Manager) newTranslator() (*operate.Translator, error) {
+	if len(m.profile) != 0 {
+		if err := geo.CheckLanguage(m.Conf.Lang); err != nil {
+			log.Debug("geo.CheckLanguage error: ", err)
+			return nil, err
+		}
+		return &operate.Translator{TargetLang: m.Conf.Lang}, nil
+	}
+
+	tl, err := operate.NewTranslator(m.Conf.Lang)
+	if err != nil {
+		log.Debug("operate.NewTranslator error: ", err)
+		return nil, err
+	}
+	return tl, nil
+}
+
 // newDataRewriter creates a DataRewriter based on the pack mode configuration.
 // It loads different rewrite rules based on whether the pack mode is enabled or not.
 func (m *Manager) newDataRewriter(isPackMode bool) (*operate.DataRewriter, error) {
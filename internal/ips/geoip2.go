@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sjzar/ips/format/mmdb"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// GeoIP2 web service endpoints.
+const (
+	GeoIP2Country  = "country"
+	GeoIP2City     = "city"
+	GeoIP2Insights = "insights"
+)
+
+// GeoIP2Me is the IP parameter standing for the client IP.
+const GeoIP2Me = "me"
+
+// GeoIP2 web service error codes.
+const (
+	GeoIP2CodeAccountIDRequired    = "ACCOUNT_ID_REQUIRED"
+	GeoIP2CodeAuthorizationInvalid = "AUTHORIZATION_INVALID"
+	GeoIP2CodeIPAddressInvalid     = "IP_ADDRESS_INVALID"
+	GeoIP2CodeIPAddressNotFound    = "IP_ADDRESS_NOT_FOUND"
+	GeoIP2CodePathNotFound         = "PATH_NOT_FOUND"
+)
+
+// geoIP2Keys lists the top-level keys of the responses of each endpoint.
+var geoIP2Keys = map[string][]string{
+	GeoIP2Country: {
+		mmdb.FieldContinent, mmdb.FieldCountry, mmdb.FieldRegisteredCountry, mmdb.FieldRepresentedCountry, "traits",
+	},
+	GeoIP2City: {
+		mmdb.FieldCity, mmdb.FieldContinent, mmdb.FieldCountry, "location", "postal",
+		mmdb.FieldRegisteredCountry, mmdb.FieldRepresentedCountry, mmdb.FieldSubdivisions, "traits",
+	},
+	GeoIP2Insights: {
+		mmdb.FieldCity, mmdb.FieldContinent, mmdb.FieldCountry, "location", "postal",
+		mmdb.FieldRegisteredCountry, mmdb.FieldRepresentedCountry, mmdb.FieldSubdivisions, "traits",
+	},
+}
+
+// geoIP2TraitFields lists the string fields that belong to the traits of the responses.
+var geoIP2TraitFields = []string{
+	"isp", "organization", mmdb.FieldAutonomousSystemOrganization, "domain", "connection_type", "user_type",
+}
+
+// GeoIP2Auth is a middleware that checks the account ID and the license key of the GeoIP2 compatible API,
+// given by HTTP basic authentication.
+func (m *Manager) GeoIP2Auth(c *gin.Context) {
+	if len(m.Conf.GeoIP2Accounts) == 0 {
+		return
+	}
+
+	account, key, ok := c.Request.BasicAuth()
+	if !ok || len(account) == 0 {
+		geoIP2Error(c, http.StatusUnauthorized, GeoIP2CodeAccountIDRequired, errors.ErrUnauthorized)
+		return
+	}
+	expected, ok := m.Conf.GeoIP2Accounts[account]
+	if !ok || subtle.ConstantTimeCompare([]byte(key), []byte(expected)) != 1 {
+		geoIP2Error(c, http.StatusUnauthorized, GeoIP2CodeAuthorizationInvalid, errors.ErrUnauthorized)
+		return
+	}
+}
+
+// GetGeoIP2 handles the GET /geoip/v2.1/{country,city,insights}/<ip> endpoints, compatible with
+// the GeoIP2 web services. The IP "me" stands for the client IP.
+// Example:
+// GET /geoip/v2.1/city/<ip>
+// Response:
+// {"city": {}, "country": {}, "location": {}, "subdivisions": [{}], "traits": {}}
+func (m *Manager) GetGeoIP2(c *gin.Context) {
+	endpoint := c.Param("endpoint")
+	if _, ok := geoIP2Keys[endpoint]; !ok {
+		geoIP2Error(c, http.StatusNotFound, GeoIP2CodePathNotFound, errors.ErrNotFound)
+		return
+	}
+
+	ip := c.Param("ip")
+	if ip == GeoIP2Me {
+		addr, err := m.clientIP.ClientIP(c.Request)
+		if err != nil {
+			geoIP2Error(c, http.StatusBadRequest, GeoIP2CodeIPAddressInvalid, err)
+			return
+		}
+		ip = addr.String()
+	}
+
+	info, err := m.parseIP(ip)
+	if err != nil {
+		if err == errors.ErrInvalidIP {
+			geoIP2Error(c, http.StatusBadRequest, GeoIP2CodeIPAddressInvalid, err)
+			return
+		}
+		geoIP2Error(c, http.StatusNotFound, GeoIP2CodeIPAddressNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, GeoIP2Response(info, endpoint))
+}
+
+// GeoIP2Response converts the IP information into the response of the GeoIP2 web service endpoint.
+// The fields are mapped to the GeoIP2 data structure in the same way as the MMDB writer.
+func GeoIP2Response(info *model.IPInfo, endpoint string) map[string]interface{} {
+	fields := model.ConvertToDBFields(info.Fields, info.FieldAlias, mmdb.CommonFieldsAlias)
+	data := mmdb.ConvertMap(fields, info.Values(), mmdb.WriterOption{})
+
+	// the MMDB data structure keeps the postal code under "postal_code"
+	if postal, ok := data[mmdb.FieldPostalCode]; ok {
+		data["postal"] = postal
+	}
+
+	traits, ok := data["traits"].(map[string]interface{})
+	if !ok {
+		traits = make(map[string]interface{})
+	}
+	for _, field := range geoIP2TraitFields {
+		if value, ok := data[field]; ok {
+			traits[field] = value
+		}
+	}
+	traits["ip_address"] = info.IP.String()
+	if ipNets := info.IPNet.IPNets(); len(ipNets) > 0 {
+		traits["network"] = ipNets[0].String()
+	}
+	data["traits"] = traits
+
+	ret := make(map[string]interface{})
+	for _, key := range geoIP2Keys[endpoint] {
+		if value, ok := data[key]; ok {
+			ret[key] = value
+		}
+	}
+	return ret
+}
+
+// geoIP2Error aborts the request with an error in the format of the GeoIP2 web services.
+func geoIP2Error(c *gin.Context, status int, code string, err error) {
+	c.AbortWithStatusJSON(status, gin.H{"code": code, "error": err.Error()})
+}
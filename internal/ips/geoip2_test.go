@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestGeoIP2Response(t *testing.T) {
+	ast := assert.New(t)
+
+	_, network, _ := net.ParseCIDR("1.0.1.0/24")
+	info := &model.IPInfo{
+		IP:    net.ParseIP("1.0.1.1"),
+		IPNet: ipnet.NewRange(network),
+		Data: map[string]string{
+			"country_name": "中国",
+			"region_name":  "广东",
+			"city_name":    "深圳",
+			"isp_domain":   "电信",
+			"latitude":     "22.5",
+			"asn":          "4134",
+		},
+		FieldAlias: map[string]string{
+			model.Country:  "country_name",
+			model.Province: "region_name",
+			model.City:     "city_name",
+			model.ISP:      "isp_domain",
+		},
+		Fields: []string{"country_name", "region_name", "city_name", "isp_domain", "latitude", "asn"},
+	}
+
+	city := GeoIP2Response(info, GeoIP2City)
+	ast.Equal("CN", city["country"].(map[string]interface{})["iso_code"])
+	ast.Equal("Shenzhen", city["city"].(map[string]interface{})["names"].(map[string]interface{})["en"])
+	ast.Len(city["subdivisions"], 1)
+	ast.Equal(22.5, city["location"].(map[string]interface{})["latitude"])
+	traits := city["traits"].(map[string]interface{})
+	ast.Equal("1.0.1.1", traits["ip_address"])
+	ast.Equal("1.0.1.0/24", traits["network"])
+	ast.EqualValues(4134, traits["autonomous_system_number"])
+	ast.Equal("电信", traits["isp"])
+
+	country := GeoIP2Response(info, GeoIP2Country)
+	ast.Contains(country, "country")
+	ast.Contains(country, "traits")
+	ast.NotContains(country, "city")
+	ast.NotContains(country, "location")
+	ast.NotContains(country, "subdivisions")
+}
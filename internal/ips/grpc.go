@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/ipspb"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// GRPCService runs the gRPC service on the configured grpc address.
+// It shares the readers with the HTTP service, and stops gracefully when ctx is done.
+func (m *Manager) GRPCService(ctx context.Context) error {
+	listener, err := net.Listen("tcp", m.Conf.GRPCAddr)
+	if err != nil {
+		log.Debug("net.Listen error: ", err)
+		return err
+	}
+
+	log.Infof("gRPC service listening on %s", listener.Addr())
+	return m.serveGRPC(ctx, listener)
+}
+
+// serveGRPC serves the gRPC service on the listener until ctx is done, then stops gracefully.
+func (m *Manager) serveGRPC(ctx context.Context, listener net.Listener) error {
+	access, err := m.grpcAccess()
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	if access != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := access(ctx); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := access(ss.Context()); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+
+	server := grpc.NewServer(opts...)
+	ipspb.RegisterIPSServer(server, &grpcServer{m: m})
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	return server.Serve(listener)
+}
+
+// grpcAccess returns a function that checks a gRPC call against the allowlist, the rate limits
+// and the API keys, in the same order as the HTTP APIs, or nil if the access is not restricted.
+// The client IP is the peer address, and every call, a stream included, takes one token.
+// The gRPC calls have their own token buckets.
+func (m *Manager) grpcAccess() (func(ctx context.Context) error, error) {
+	allowlist, err := parsePrefixes(m.Conf.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
+	perKey := newRateLimiters(m.Conf.RateLimitPerKey, m.Conf.RateLimitBurst)
+	perIP := newRateLimiters(m.Conf.RateLimitPerIP, m.Conf.RateLimitBurst)
+	if len(allowlist) == 0 && perKey == nil && perIP == nil && len(m.Conf.APIKeys) == 0 {
+		return nil, nil
+	}
+
+	return func(ctx context.Context) error {
+		ip, err := peerAddr(ctx)
+		if len(allowlist) != 0 && (err != nil || !containsAddr(allowlist, ip)) {
+			return status.Error(codes.PermissionDenied, errors.ErrForbidden.Error())
+		}
+		key := grpcAPIKey(ctx)
+		if m.validAPIKey(key) && !perKey.allow(key) {
+			return status.Error(codes.ResourceExhausted, errors.ErrRateLimited.Error())
+		}
+		if err == nil && !perIP.allow(ip.String()) {
+			return status.Error(codes.ResourceExhausted, errors.ErrRateLimited.Error())
+		}
+		if len(m.Conf.APIKeys) != 0 && !m.validAPIKey(key) {
+			return status.Error(codes.Unauthenticated, errors.ErrUnauthorized.Error())
+		}
+		return nil
+	}, nil
+}
+
+// peerAddr returns the IP address of the gRPC peer.
+func peerAddr(ctx context.Context) (netip.Addr, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, errors.ErrInvalidIP
+	}
+	addrPort, err := netip.ParseAddrPort(p.Addr.String())
+	if err != nil {
+		log.Debug("netip.ParseAddrPort error: ", err)
+		return netip.Addr{}, errors.ErrInvalidIP
+	}
+	return addrPort.Addr().Unmap(), nil
+}
+
+// grpcAPIKey returns the API key of a gRPC call, from the x-api-key metadata or the bearer token.
+func grpcAPIKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(APIKeyHeader); len(values) != 0 && len(values[0]) != 0 {
+		return values[0]
+	}
+	for _, auth := range md.Get("authorization") {
+		if strings.HasPrefix(auth, "Bearer ") {
+			return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		}
+	}
+	return ""
+}
+
+// grpcServer implements ipspb.IPSServer with the readers of the Manager.
+type grpcServer struct {
+	ipspb.UnimplementedIPSServer
+	m *Manager
+}
+
+// Lookup retrieves the information of an IP address.
+func (s *grpcServer) Lookup(_ context.Context, req *ipspb.LookupRequest) (*ipspb.IPInfo, error) {
+	info, err := s.m.parseIP(req.GetIp())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return s.ipInfo(info), nil
+}
+
+// BatchLookup retrieves the information of a stream of IP addresses, in order.
+// Failed lookups are reported in the responses instead of ending the stream.
+func (s *grpcServer) BatchLookup(stream ipspb.IPS_BatchLookupServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &ipspb.BatchLookupResponse{Ip: req.GetIp()}
+		if info, err := s.m.parseIP(req.GetIp()); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Info = s.ipInfo(info)
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// ParseText finds the IP addresses and domains in a text and retrieves their information.
+func (s *grpcServer) ParseText(_ context.Context, req *ipspb.ParseTextRequest) (*ipspb.ParseTextResponse, error) {
+	resp := &ipspb.ParseTextResponse{}
+
+	tp := parser.NewTextParser(req.GetText()).Parse()
+	for _, segment := range tp.Segments {
+		info, err := s.m.parseSegment(segment)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+
+		switch v := info.(type) {
+		case *model.IPInfo:
+			resp.Items = append(resp.Items, s.ipInfo(v))
+		case *model.DomainInfo:
+			resp.Domains = append(resp.Domains, &ipspb.DomainInfo{
+				Domain:     v.Domain,
+				MainDomain: v.MainDomain,
+				Data:       v.Data,
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+// Meta returns the meta-information of the IPv4 and IPv6 databases, loading them if necessary.
+// A database that fails to load is omitted.
+func (s *grpcServer) Meta(context.Context, *ipspb.MetaRequest) (*ipspb.MetaResponse, error) {
+	resp := &ipspb.MetaResponse{}
+	if version, err := readerVersion(&s.m.ipv4, s.m.loadIPv4Reader); err == nil {
+		resp.Ipv4 = databaseMeta(version)
+	}
+	if version, err := readerVersion(&s.m.ipv6, s.m.loadIPv6Reader); err == nil {
+		resp.Ipv6 = databaseMeta(version)
+	}
+	return resp, nil
+}
+
+// ipInfo converts the IP information to the message, the same as the JSON output.
+func (s *grpcServer) ipInfo(info *model.IPInfo) *ipspb.IPInfo {
+	output := info.Output(s.m.Conf.UseDBFields)
+	return &ipspb.IPInfo{
+		Ip:   output.IP,
+		Net:  output.Net,
+		Data: output.Data,
+	}
+}
+
+// databaseMeta converts the database version to the message.
+func databaseMeta(version *DatabaseVersion) *ipspb.DatabaseMeta {
+	meta := &ipspb.DatabaseMeta{
+		Format:    version.Format,
+		IpVersion: int32(version.IPVersion),
+		Fields:    version.Fields,
+		LoadTime:  version.LoadTime.Unix(),
+	}
+	for _, file := range version.Files {
+		meta.Files = append(meta.Files, &ipspb.DatabaseFile{
+			Path:    file.Path,
+			Size:    file.Size,
+			ModTime: file.ModTime.Unix(),
+			Sha256:  file.SHA256,
+		})
+	}
+	return meta
+}
+
+// grpcError converts the error to a gRPC status error with the code of the error.
+func grpcError(err error) error {
+	switch err {
+	case errors.ErrInvalidIP, errors.ErrInvalidIPRange, errors.ErrUnsupportedIPVersion:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.ErrNotFound, errors.ErrFileNotFound, errors.ErrProfileNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
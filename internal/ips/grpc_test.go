@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/ipspb"
+)
+
+func TestGRPCService(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- m.serveGRPC(ctx, listener)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	client := ipspb.NewIPSClient(conn)
+
+	info, err := client.Lookup(context.Background(), &ipspb.LookupRequest{Ip: "200.1.1.1"})
+	ast.Nil(err)
+	ast.Equal("200.1.1.1", info.GetIp())
+	ast.Equal("192.0.0.0/3", info.GetNet())
+	ast.Equal(map[string]string{"country": "中国", "city": "深圳"}, info.GetData())
+
+	_, err = client.Lookup(context.Background(), &ipspb.LookupRequest{Ip: "bogus"})
+	ast.Equal(codes.InvalidArgument, status.Code(err))
+
+	// the stream in flight is served to the end after the service is stopped
+	stream, err := client.BatchLookup(context.Background())
+	ast.Nil(err)
+	ast.Nil(stream.Send(&ipspb.LookupRequest{Ip: "130.0.0.1"}))
+	resp, err := stream.Recv()
+	ast.Nil(err)
+	ast.Equal("美国", resp.GetInfo().GetData()["country"])
+
+	cancel()
+	select {
+	case <-served:
+		t.Fatal("the service stopped before the stream in flight ended")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ast.Nil(stream.Send(&ipspb.LookupRequest{Ip: "bogus"}))
+	resp, err = stream.Recv()
+	ast.Nil(err)
+	ast.Equal("invalid IP address", resp.GetError())
+	ast.Nil(stream.CloseSend())
+	_, err = stream.Recv()
+	ast.Equal(io.EOF, err)
+
+	select {
+	case err := <-served:
+		ast.Nil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the service did not stop")
+	}
+
+	_, err = client.Lookup(context.Background(), &ipspb.LookupRequest{Ip: "200.1.1.1"})
+	ast.NotNil(err)
+}
+
+func TestGRPCAccess(t *testing.T) {
+	ast := assert.New(t)
+
+	dial := func(m *Manager) ipspb.IPSClient {
+		listener := bufconn.Listen(1 << 20)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go func() {
+			_ = m.serveGRPC(ctx, listener)
+		}()
+
+		conn, err := grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		return ipspb.NewIPSClient(conn)
+	}
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+	req := &ipspb.LookupRequest{Ip: "200.1.1.1"}
+
+	m := newTestManager(t, "country")
+	m.Conf.APIKeys = []string{"k1"}
+	m.Conf.RateLimitPerKey = 1
+	m.Conf.RateLimitBurst = 1
+	client := dial(m)
+
+	_, err := client.Lookup(context.Background(), req)
+	ast.Equal(codes.Unauthenticated, status.Code(err))
+	_, err = client.Lookup(withKey("k2"), req)
+	ast.Equal(codes.Unauthenticated, status.Code(err))
+	_, err = client.Lookup(withKey("k1"), req)
+	ast.Nil(err)
+	_, err = client.Lookup(withKey("k1"), req)
+	ast.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// the streams are checked as well
+	stream, err := client.BatchLookup(context.Background())
+	ast.Nil(err)
+	_, err = stream.Recv()
+	ast.Equal(codes.Unauthenticated, status.Code(err))
+
+	// the bufconn peer has no IP address, so it is out of any allowlist
+	m = newTestManager(t, "country")
+	m.Conf.AllowIPs = []string{"127.0.0.1"}
+	_, err = dial(m).Lookup(context.Background(), req)
+	ast.Equal(codes.PermissionDenied, status.Code(err))
+
+	m.Conf.AllowIPs = []string{"bogus"}
+	ast.Equal(errors.ErrInvalidCIDR, m.serveGRPC(context.Background(), bufconn.Listen(1<<10)))
+}
+
+func TestGRPCError(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(codes.InvalidArgument, status.Code(grpcError(errors.ErrInvalidIP)))
+	ast.Equal(codes.InvalidArgument, status.Code(grpcError(errors.ErrUnsupportedIPVersion)))
+	ast.Equal(codes.NotFound, status.Code(grpcError(errors.ErrFileNotFound)))
+	ast.Equal(codes.NotFound, status.Code(grpcError(errors.ErrProfileNotFound)))
+	ast.Equal(codes.Internal, status.Code(grpcError(errors.ErrInvalidDatabase)))
+}
+
+func TestServiceGRPCFailure(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := newTestManager(t, "country")
+	m.Conf.Addr = "127.0.0.1:0"
+	m.Conf.GRPCAddr = "256.0.0.1:0"
+
+	served := make(chan error, 1)
+	go func() {
+		served <- m.Service()
+	}()
+
+	// the gRPC failure stops the HTTP service, and is returned
+	select {
+	case err := <-served:
+		ast.NotNil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the service did not stop")
+	}
+}
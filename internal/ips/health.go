@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// PreloadRetryInterval is the interval to retry loading the databases that failed to load on startup.
+const PreloadRetryInterval = 10 * time.Second
+
+// Reader status reported by the readiness endpoint.
+const (
+	ReaderStatusReady    = "ready"
+	ReaderStatusLoading  = "loading"
+	ReaderStatusDisabled = "disabled"
+)
+
+// DatabaseMeta describes the metadata and the version of a loaded database reader.
+type DatabaseMeta struct {
+	Meta    *model.Meta      `json:"meta"`
+	Version *DatabaseVersion `json:"version"`
+	Cache   *ipio.CacheStats `json:"cache,omitempty"`
+}
+
+// readerSlot is a reader holder with the way to load it.
+type readerSlot struct {
+	name       string
+	configured bool
+	holder     *readerHolder
+	load       func() (*readerHandle, error)
+}
+
+// readerSlots returns the IPv4 and IPv6 reader slots.
+func (m *Manager) readerSlots() []readerSlot {
+	return []readerSlot{
+		{name: "ipv4", configured: len(m.Conf.IPv4File) != 0, holder: &m.ipv4, load: m.loadIPv4Reader},
+		{name: "ipv6", configured: len(m.Conf.IPv6File) != 0, holder: &m.ipv6, load: m.loadIPv6Reader},
+	}
+}
+
+// Preload loads the configured readers in the background of the service, instead of on the first lookup.
+// The readers that fail to load are retried until ctx is done.
+func (m *Manager) Preload(ctx context.Context) {
+	for {
+		ready := true
+		for _, slot := range m.readerSlots() {
+			if !slot.configured || slot.holder.loaded() != nil {
+				continue
+			}
+			handle, err := slot.holder.acquire(slot.load)
+			if err != nil {
+				log.Errorf("load %s database failed: %s", slot.name, err)
+				ready = false
+				continue
+			}
+			handle.release()
+		}
+		if ready {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(PreloadRetryInterval):
+		}
+	}
+}
+
+// Healthz handles the GET /healthz endpoint for liveness probes.
+// It responds as long as the service is running.
+// Example:
+// GET /healthz
+// Response:
+// {"status": "ok"}
+func (m *Manager) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz handles the GET /readyz endpoint for readiness probes.
+// It responds 200 once the configured IPv4 and IPv6 readers are loaded, and 503 before.
+// Example:
+// GET /readyz
+// Response:
+// {"status": "ok", "ipv4": "ready", "ipv6": "ready"}
+func (m *Manager) Readyz(c *gin.Context) {
+	code, status := http.StatusOK, "ok"
+	ret := gin.H{}
+	for _, slot := range m.readerSlots() {
+		switch {
+		case !slot.configured:
+			ret[slot.name] = ReaderStatusDisabled
+		case slot.holder.loaded() != nil:
+			ret[slot.name] = ReaderStatusReady
+		default:
+			ret[slot.name] = ReaderStatusLoading
+			code, status = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+	ret["status"] = status
+	c.JSON(code, ret)
+}
+
+// GetMeta handles the GET /v1/meta endpoint. It returns the metadata and the version
+// of the loaded IPv4 and IPv6 databases.
+// Example:
+// GET /v1/meta
+// Response:
+// {"ipv4": {"meta": {}, "version": {}}, "ipv6": {}}
+func (m *Manager) GetMeta(c *gin.Context) {
+	ret := make(map[string]*DatabaseMeta)
+	for _, slot := range m.readerSlots() {
+		if handle := slot.holder.loaded(); handle != nil {
+			ret[slot.name] = &DatabaseMeta{
+				Meta:    handle.Meta(),
+				Version: handle.version,
+			}
+			if handle.cache != nil {
+				ret[slot.name].Cache = handle.cache.Stats()
+			}
+		}
+	}
+	c.JSON(http.StatusOK, ret)
+}
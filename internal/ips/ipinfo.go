@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sjzar/ips/format/geo"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// IPInfoIO represents the response of the ipinfo.io compatible API.
+type IPInfoIO struct {
+	IP       string `json:"ip"`
+	City     string `json:"city,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Loc      string `json:"loc,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Postal   string `json:"postal,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// NewIPInfoIO converts the IP information into the ipinfo.io data structure.
+// The country is converted to its ISO code, "loc" is built from the latitude and longitude,
+// and "org" is built from the ASN and the organization, e.g. "AS15169 Google LLC".
+func NewIPInfoIO(info *model.IPInfo) *IPInfoIO {
+	data := info.Output(true).Data
+	get := func(fields ...string) string {
+		for _, field := range fields {
+			if v := data[field]; len(v) != 0 {
+				return v
+			}
+			if dbField, ok := info.FieldAlias[field]; ok && len(data[dbField]) != 0 {
+				return data[dbField]
+			}
+		}
+		return ""
+	}
+
+	ret := &IPInfoIO{
+		IP:       info.IP.String(),
+		City:     get(model.City),
+		Region:   get(model.Province),
+		Country:  get("country_code", "iso_code"),
+		Postal:   get("postal_code", "zip_code"),
+		Timezone: get("time_zone", "timezone", model.UTCOffset),
+	}
+
+	if len(ret.Country) == 0 {
+		ret.Country = get(model.Country)
+		if geoInfo, ok := geo.GetInfoByName(model.Country, ret.Country); ok && len(geoInfo.IsoCode) != 0 {
+			ret.Country = geoInfo.IsoCode
+		}
+	}
+
+	if lat, lon := get(model.Latitude), get(model.Longitude); len(lat) != 0 && len(lon) != 0 {
+		ret.Loc = lat + "," + lon
+	}
+
+	asn := get(model.ASN)
+	org := get("autonomous_system_organization", "organization", model.ISP)
+	if len(asn) != 0 {
+		if !strings.HasPrefix(strings.ToUpper(asn), "AS") {
+			asn = "AS" + asn
+		}
+		org = strings.TrimSpace(asn + " " + org)
+	}
+	ret.Org = org
+
+	return ret
+}
+
+// Field returns the value of the field in the ipinfo.io data structure.
+func (i *IPInfoIO) Field(field string) (string, bool) {
+	switch field {
+	case "ip":
+		return i.IP, true
+	case "city":
+		return i.City, true
+	case "region":
+		return i.Region, true
+	case "country":
+		return i.Country, true
+	case "loc":
+		return i.Loc, true
+	case "org":
+		return i.Org, true
+	case "postal":
+		return i.Postal, true
+	case "timezone":
+		return i.Timezone, true
+	}
+	return "", false
+}
+
+// GetIPInfo handles the GET /ipinfo/<ip> endpoint, compatible with ipinfo.io.
+// Without the IP, it returns the information of the client IP.
+// Example:
+// GET /ipinfo/<ip>
+// Response:
+// {"ip": "<ip>", "city": "", "region": "", "country": "", "loc": "", "org": "", "timezone": ""}
+func (m *Manager) GetIPInfo(c *gin.Context) {
+	ret, err := m.ipInfoIO(c, c.Param("ip"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// GetIPInfoField handles the GET /ipinfo/<ip>/<field> endpoint, compatible with ipinfo.io.
+// It returns the value of the field in plain text.
+// Example:
+// GET /ipinfo/<ip>/city
+// Response:
+// <city>
+func (m *Manager) GetIPInfoField(c *gin.Context) {
+	ret, err := m.ipInfoIO(c, c.Param("ip"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, ok := ret.Field(c.Param("field"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.ErrFieldInvalid.Error()})
+		return
+	}
+
+	c.String(http.StatusOK, "%s\n", value)
+}
+
+// PostIPInfoBatch handles the POST /ipinfo/batch endpoint, compatible with ipinfo.io.
+// It takes a JSON array of "<ip>" or "<ip>/<field>" and returns an object keyed by them.
+// Example:
+// POST /ipinfo/batch
+// ["8.8.8.8", "8.8.8.8/country"]
+// Response:
+// {"8.8.8.8": {}, "8.8.8.8/country": "US"}
+func (m *Manager) PostIPInfoBatch(c *gin.Context) {
+	var items []string
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrInvalidBatch.Error()})
+		return
+	}
+	if m.Conf.BatchMaxSize > 0 && len(items) > m.Conf.BatchMaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.ErrBatchTooLarge.Error()})
+		return
+	}
+
+	ret := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		ip, field, hasField := strings.Cut(strings.TrimPrefix(item, "/"), "/")
+		info, err := m.ipInfoIO(c, ip)
+		if err != nil {
+			ret[item] = gin.H{"error": err.Error()}
+			continue
+		}
+		if !hasField {
+			ret[item] = info
+			continue
+		}
+		value, ok := info.Field(field)
+		if !ok {
+			ret[item] = gin.H{"error": errors.ErrFieldInvalid.Error()}
+			continue
+		}
+		ret[item] = value
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// ipInfoIO looks up the IP, or the client IP if empty, in the ipinfo.io data structure.
+func (m *Manager) ipInfoIO(c *gin.Context, ip string) (*IPInfoIO, error) {
+	if len(ip) == 0 {
+		addr, err := m.clientIP.ClientIP(c.Request)
+		if err != nil {
+			return nil, err
+		}
+		ip = addr.String()
+	}
+
+	info, err := m.parseIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return NewIPInfoIO(info), nil
+}
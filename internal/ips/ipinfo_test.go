@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestNewIPInfoIO(t *testing.T) {
+	ast := assert.New(t)
+
+	_, network, _ := net.ParseCIDR("8.8.8.0/24")
+	info := &model.IPInfo{
+		IP:    net.ParseIP("8.8.8.8"),
+		IPNet: ipnet.NewRange(network),
+		Data: map[string]string{
+			"city":                           "Mountain View",
+			"subdivisions":                   "California",
+			"country":                        "美国",
+			"latitude":                       "37.4056",
+			"longitude":                      "-122.0775",
+			"time_zone":                      "America/Los_Angeles",
+			"autonomous_system_number":       "15169",
+			"autonomous_system_organization": "Google LLC",
+		},
+		FieldAlias: map[string]string{
+			model.Country:  "country",
+			model.Province: "subdivisions",
+			model.City:     "city",
+			model.ASN:      "autonomous_system_number",
+		},
+		Fields: []string{"city", "subdivisions", "country", "latitude", "longitude", "time_zone",
+			"autonomous_system_number", "autonomous_system_organization"},
+	}
+
+	ret := NewIPInfoIO(info)
+	ast.Equal(&IPInfoIO{
+		IP:       "8.8.8.8",
+		City:     "Mountain View",
+		Region:   "California",
+		Country:  "US",
+		Loc:      "37.4056,-122.0775",
+		Org:      "AS15169 Google LLC",
+		Timezone: "America/Los_Angeles",
+	}, ret)
+
+	value, ok := ret.Field("loc")
+	ast.True(ok)
+	ast.Equal("37.4056,-122.0775", value)
+	_, ok = ret.Field("hostname")
+	ast.False(ok)
+
+	// ISP without ASN
+	info.Fields = []string{"country", "isp"}
+	info.Data["isp"] = "电信"
+	ret = NewIPInfoIO(info)
+	ast.Equal("电信", ret.Org)
+	ast.Empty(ret.Loc)
+}
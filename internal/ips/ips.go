@@ -18,8 +18,6 @@ package ips
 
 import (
 	"github.com/gin-gonic/gin"
-
-	"github.com/sjzar/ips/format"
 )
 
 // Manager is a command-line tool for IP operations.
@@ -28,12 +26,31 @@ type Manager struct {
 	Conf *Config
 
 	// IPv4 and IPv6 are the IP readers for their respective IP versions.
-	ipv4 format.Reader
-	ipv6 format.Reader
+	// They are loaded lazily and can be reloaded at runtime.
+	ipv4 readerHolder
+	ipv6 readerHolder
 
 	// router is the HTTP router.
 	router *gin.Engine
 
+	// clientIP resolves the client IP of the HTTP requests.
+	clientIP *ClientIPResolver
+
+	// profile is the name of the profile of a Manager created for requests, empty for the Manager itself.
+	profile string
+
+	// profiles caches the Managers of the profiles selected by requests.
+	profiles profileCache
+
+	// output keeps the output across the calls of ParseText.
+	output outputState
+
+	// template is the text template of the output.
+	template textTemplate
+
+	// stats accumulates the results of ParseText if the statistics is enabled.
+	stats *statsState
+
 	mdns *MDNS
 }
 
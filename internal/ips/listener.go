@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// UnixAddrPrefix marks a unix socket listen address, e.g. unix:/run/ips.sock.
+	UnixAddrPrefix = "unix:"
+
+	// CertReloadInterval is the minimum interval to check the certificate files for changes.
+	CertReloadInterval = 10 * time.Second
+)
+
+// ListenAddrs returns the listen addresses of the service, separated by commas in the configuration.
+func (c *Config) ListenAddrs() []string {
+	ret := make([]string, 0)
+	for _, addr := range strings.Split(c.Addr, ",") {
+		if addr = strings.TrimSpace(addr); len(addr) != 0 {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// Listen listens on a TCP address, or a unix socket address with the "unix:" prefix.
+// A stale unix socket file left by a previous process is removed.
+func Listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, UnixAddrPrefix) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, UnixAddrPrefix)
+	if stat, err := os.Stat(path); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			log.Debug("os.Remove error: ", err)
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// certReloader loads a TLS certificate and reloads it when the certificate or key file changes,
+// so that renewed certificates are served without restarting.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader creates a certReloader and loads the certificate.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(r.latestModTime()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, implementing tls.Config.GetCertificate.
+// The previous certificate is kept if the changed files fail to load.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.lastCheck) >= CertReloadInterval {
+		r.lastCheck = time.Now()
+		if modTime := r.latestModTime(); modTime.After(r.modTime) {
+			if err := r.load(modTime); err != nil {
+				log.Error("Failed to reload certificate:", err)
+			} else {
+				log.Info("certificate reloaded: ", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// load loads the certificate, which has been modified at modTime.
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		log.Debug("tls.LoadX509KeyPair error: ", err)
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the latest modification time of the certificate and key files.
+func (r *certReloader) latestModTime() time.Time {
+	var ret time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if stat, err := os.Stat(file); err == nil && stat.ModTime().After(ret) {
+			ret = stat.ModTime()
+		}
+	}
+	return ret
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen(t *testing.T) {
+	ast := assert.New(t)
+
+	conf := &Config{Addr: " :6860, unix:/run/ips.sock,,127.0.0.1:6861"}
+	ast.Equal([]string{":6860", "unix:/run/ips.sock", "127.0.0.1:6861"}, conf.ListenAddrs())
+
+	listener, err := Listen("127.0.0.1:0")
+	ast.Nil(err)
+	ast.Equal("tcp", listener.Addr().Network())
+	_ = listener.Close()
+
+	// a stale socket file is replaced
+	path := filepath.Join(t.TempDir(), "ips.sock")
+	stale, err := net.Listen("unix", path)
+	ast.Nil(err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	listener, err = Listen(UnixAddrPrefix + path)
+	ast.Nil(err)
+	ast.Equal("unix", listener.Addr().Network())
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	_ = conn.Close()
+	_ = listener.Close()
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/util"
+)
+
+// ManifestFile is the name of the file in the ips dir that records the downloaded database files.
+const ManifestFile = "manifest.json"
+
+// manifestMu serializes manifest updates within the process.
+var manifestMu sync.Mutex
+
+// Manifest records the version information of the database files downloaded to the ips dir.
+type Manifest struct {
+	// Files maps database file names to their version information.
+	Files map[string]*ManifestEntry `json:"files"`
+}
+
+// ManifestEntry holds the version information of a downloaded database file.
+type ManifestEntry struct {
+	// File is the name of the database file in the ips dir.
+	File string `json:"file"`
+
+	// URL is the resolved URL that the file was downloaded from.
+	URL string `json:"url"`
+
+	// ETag is the ETag header returned by the server, used for conditional requests.
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the Last-Modified header returned by the server, used for conditional requests.
+	LastModified string `json:"last_modified,omitempty"`
+
+	// SHA256 is the hex encoded sha256 checksum of the stored file.
+	SHA256 string `json:"sha256"`
+
+	// DownloadTime is the time when the file was downloaded.
+	DownloadTime time.Time `json:"download_time"`
+}
+
+// LoadManifest loads the manifest from the ips dir.
+// An empty manifest is returned if the manifest file does not exist yet.
+func LoadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{
+		Files: make(map[string]*ManifestEntry),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		log.Debug("json.Unmarshal error: ", err)
+		return nil, err
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]*ManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest to the ips dir.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := util.CreateAtomicFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Abort()
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	return f.Commit(nil)
+}
+
+// updateManifest records the entry in the manifest of the ips dir.
+func updateManifest(dir string, entry *ManifestEntry) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	manifest.Files[entry.File] = entry
+
+	return manifest.Save(dir)
+}
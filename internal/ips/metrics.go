@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/sjzar/ips/format/ipdb/sdk"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// MetricsNamespace is the namespace of the metrics exposed by the server.
+const MetricsNamespace = "ips"
+
+// Error types of the failed lookups. The errors are counted by a fixed set of types,
+// so that the error messages do not create unbounded label values.
+const (
+	LookupErrorInvalidIP       = "invalid_ip"
+	LookupErrorUnsupportedIP   = "unsupported_ip_version"
+	LookupErrorNotFound        = "not_found"
+	LookupErrorInvalidDatabase = "invalid_database"
+	LookupErrorOther           = "other"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	lookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "lookups_total",
+		Help:      "Number of IP lookups by reader and database format.",
+	}, []string{"reader", "format"})
+
+	lookupErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "lookup_errors_total",
+		Help:      "Number of failed IP lookups by reader and error type.",
+	}, []string{"reader", "error"})
+
+	databaseInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "database_info",
+		Help:      "Information of the loaded database files, the value is the load time in unix seconds.",
+	}, []string{"reader", "format", "file", "sha256"})
+
+	hybridLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "hybrid_source_lookups_total",
+		Help:      "Number of lookups of each source of hybrid readers by result, hit or miss.",
+	}, []string{"reader", "source", "result"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "cache_lookups_total",
+		Help:      "Number of lookups of the reader caches by result, hit or miss.",
+	}, []string{"reader", "result"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		lookups,
+		lookupErrors,
+		databaseInfo,
+		hybridLookups,
+		cacheLookups,
+	)
+}
+
+// MetricsHandler returns the handler of the /metrics endpoint.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// MetricsMiddleware records the count and latency of the requests by route.
+func MetricsMiddleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if len(route) == 0 {
+		route = "unmatched"
+	}
+	httpRequests.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+	httpRequestDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+}
+
+// observeLookup records an IP lookup of the reader.
+func observeLookup(reader *readerHandle, err error) {
+	lookups.WithLabelValues(reader.name, reader.version.Format).Inc()
+	if err != nil {
+		lookupErrors.WithLabelValues(reader.name, errorType(err)).Inc()
+	}
+}
+
+// observeDatabase records the database files of a loaded reader, replacing the previous ones.
+func observeDatabase(reader *readerHandle) {
+	databaseInfo.DeletePartialMatch(prometheus.Labels{"reader": reader.name})
+	for _, file := range reader.version.Files {
+		databaseInfo.WithLabelValues(reader.name, reader.version.Format, file.Path, file.SHA256).
+			Set(float64(reader.version.LoadTime.Unix()))
+	}
+}
+
+// observeHybridSource records whether a source of a hybrid reader found the IP.
+func observeHybridSource(reader, source string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	hybridLookups.WithLabelValues(reader, source, result).Inc()
+}
+
+// observeCache records whether a lookup of the reader was found in its cache.
+func observeCache(reader string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(reader, result).Inc()
+}
+
+// errorType returns the type of a lookup error, wrapped errors are typed by their cause.
+func errorType(err error) string {
+	for {
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok || wrapper.Unwrap() == nil {
+			break
+		}
+		err = wrapper.Unwrap()
+	}
+
+	switch err {
+	case errors.ErrInvalidIP, sdk.ErrIPFormat:
+		return LookupErrorInvalidIP
+	case errors.ErrUnsupportedIPVersion, sdk.ErrNoSupportIPv4, sdk.ErrNoSupportIPv6:
+		return LookupErrorUnsupportedIP
+	case sdk.ErrDataNotExists:
+		return LookupErrorNotFound
+	case errors.ErrInvalidDatabase, errors.ErrInvalidFormat, errors.ErrMismatchedFieldsLength,
+		sdk.ErrDatabaseError, sdk.ErrFileSize, sdk.ErrMetaData, sdk.ErrReadFull:
+		return LookupErrorInvalidDatabase
+	}
+	return LookupErrorOther
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/format/ipdb/sdk"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// scrapeMetrics returns the samples exposed on /metrics of the router, by the name with the labels.
+func scrapeMetrics(t *testing.T, router http.Handler) map[string]float64 {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: %d", w.Code)
+	}
+
+	ret := make(map[string]float64)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		ret[line[:i]] = value
+	}
+	return ret
+}
+
+func TestMetrics(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := newTestManager(t, "country")
+	// the IPv4 database does not support IPv6 lookups
+	m.Conf.IPv6File = m.Conf.IPv4File
+	m.Conf.IPv6Format = m.Conf.IPv4Format
+	m.Conf.Metrics = true
+	m.router = gin.New()
+	m.router.Use(MetricsMiddleware)
+	m.InitRouter()
+
+	do := func(target string) int {
+		w := httptest.NewRecorder()
+		m.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	const (
+		requestsOK     = `ips_http_requests_total{code="200",method="GET",route="/api/v1/ip"}`
+		requestsFailed = `ips_http_requests_total{code="400",method="GET",route="/api/v1/ip"}`
+		unmatched      = `ips_http_requests_total{code="404",method="GET",route="unmatched"}`
+		durationCount  = `ips_http_request_duration_seconds_count{method="GET",route="/api/v1/ip"}`
+		durationBucket = `ips_http_request_duration_seconds_bucket{method="GET",route="/api/v1/ip",le="+Inf"}`
+		lookupsIPv4    = `ips_lookups_total{format="plain",reader="ipv4"}`
+		lookupsIPv6    = `ips_lookups_total{format="plain",reader="ipv6"}`
+		errorsIPv6     = `ips_lookup_errors_total{error="unsupported_ip_version",reader="ipv6"}`
+	)
+
+	// the metrics are global, so the changes are checked
+	before := scrapeMetrics(t, m.router)
+	ast.Equal(http.StatusOK, do("/api/v1/ip?ip=200.1.1.1"))
+	ast.Equal(http.StatusOK, do("/api/v1/ip?ip=1.1.1.1"))
+	ast.Equal(http.StatusBadRequest, do("/api/v1/ip?ip=::1"))
+	ast.Equal(http.StatusNotFound, do("/api/unknown"))
+	after := scrapeMetrics(t, m.router)
+
+	ast.Equal(2.0, after[requestsOK]-before[requestsOK])
+	ast.Equal(1.0, after[requestsFailed]-before[requestsFailed])
+	ast.Equal(1.0, after[unmatched]-before[unmatched])
+	ast.Equal(3.0, after[durationCount]-before[durationCount])
+	ast.Equal(3.0, after[durationBucket]-before[durationBucket])
+	ast.Equal(2.0, after[lookupsIPv4]-before[lookupsIPv4])
+	ast.Equal(1.0, after[lookupsIPv6]-before[lookupsIPv6])
+	ast.Equal(1.0, after[errorsIPv6]-before[errorsIPv6])
+
+	found := false
+	for sample, value := range after {
+		if strings.HasPrefix(sample, `ips_database_info{`) && strings.Contains(sample, `reader="ipv4"`) {
+			found = true
+			ast.Greater(value, 0.0)
+		}
+	}
+	ast.True(found)
+}
+
+func TestErrorType(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(LookupErrorInvalidIP, errorType(errors.ErrInvalidIP))
+	ast.Equal(LookupErrorInvalidIP, errorType(fmt.Errorf("find: %w", sdk.ErrIPFormat)))
+	ast.Equal(LookupErrorUnsupportedIP, errorType(sdk.ErrNoSupportIPv6))
+	ast.Equal(LookupErrorNotFound, errorType(sdk.ErrDataNotExists))
+	ast.Equal(LookupErrorInvalidDatabase, errorType(errors.ErrInvalidDatabase))
+	ast.Equal(LookupErrorOther, errorType(errors.ErrFileEmpty))
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+const (
+	// APIKeyHeader is the header carrying the API key.
+	APIKeyHeader = "X-API-Key"
+
+	// RateLimitPurgeInterval is the interval to purge the idle rate limiters.
+	RateLimitPurgeInterval = time.Minute
+)
+
+// APIKeyQueries lists the query parameters carrying the API key.
+// "token" is accepted for the ipinfo.io clients.
+var APIKeyQueries = []string{"key", "token"}
+
+// CORSMiddleware returns a middleware that applies the CORS policy of the configured origins,
+// or nil if CORS is disabled. An origin without a scheme or with more than one "*" is invalid.
+func (m *Manager) CORSMiddleware() (gin.HandlerFunc, error) {
+	if len(m.Conf.CORSAllowOrigins) == 0 {
+		return nil, nil
+	}
+
+	config := cors.DefaultConfig()
+	config.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	config.AllowHeaders = append(config.AllowHeaders, APIKeyHeader, "Authorization")
+	config.MaxAge = 12 * time.Hour
+	for _, origin := range m.Conf.CORSAllowOrigins {
+		if origin == "*" {
+			config.AllowAllOrigins = true
+			return cors.New(config), nil
+		}
+	}
+	config.AllowOrigins = m.Conf.CORSAllowOrigins
+	config.AllowWildcard = true
+
+	// cors.New panics on an invalid config
+	if err := config.Validate(); err != nil {
+		log.Debug("config.Validate error: ", err)
+		return nil, errors.ErrInvalidOrigin
+	}
+	for _, origin := range config.AllowOrigins {
+		if strings.Count(origin, "*") > 1 {
+			return nil, errors.ErrInvalidOrigin
+		}
+	}
+	return cors.New(config), nil
+}
+
+// AllowlistMiddleware returns a middleware that rejects the clients out of the configured IPs or CIDRs,
+// or nil if every client is allowed.
+func (m *Manager) AllowlistMiddleware() (gin.HandlerFunc, error) {
+	if len(m.Conf.AllowIPs) == 0 {
+		return nil, nil
+	}
+
+	allowlist, err := parsePrefixes(m.Conf.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		ip, err := m.clientIP.ClientIP(c.Request)
+		if err != nil || !containsAddr(allowlist, ip) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errors.ErrForbidden.Error()})
+			return
+		}
+	}, nil
+}
+
+// RateLimitMiddleware returns a middleware that limits the requests per API key and per client IP
+// with token buckets, or nil if no rate limit is configured.
+// Only the configured API keys have their own buckets, other keys are limited per client IP.
+func (m *Manager) RateLimitMiddleware() gin.HandlerFunc {
+	perKey := newRateLimiters(m.Conf.RateLimitPerKey, m.Conf.RateLimitBurst)
+	perIP := newRateLimiters(m.Conf.RateLimitPerIP, m.Conf.RateLimitBurst)
+	if perKey == nil && perIP == nil {
+		return nil
+	}
+
+	return func(c *gin.Context) {
+		if key := apiKey(c); m.validAPIKey(key) && !perKey.allow(key) {
+			rateLimited(c, perKey)
+			return
+		}
+		if ip, err := m.clientIP.ClientIP(c.Request); err == nil && !perIP.allow(ip.String()) {
+			rateLimited(c, perIP)
+			return
+		}
+	}
+}
+
+// APIKeyMiddleware returns a middleware that requires one of the configured API keys,
+// or nil if no API key is configured.
+func (m *Manager) APIKeyMiddleware() gin.HandlerFunc {
+	if len(m.Conf.APIKeys) == 0 {
+		return nil
+	}
+
+	return func(c *gin.Context) {
+		if !m.validAPIKey(apiKey(c)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errors.ErrUnauthorized.Error()})
+			return
+		}
+	}
+}
+
+// validAPIKey reports whether key is one of the configured API keys.
+func (m *Manager) validAPIKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, expected := range m.Conf.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(expected)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey returns the API key of the request, from the X-API-Key header, the bearer token,
+// the password of the basic authentication used by the GeoIP2 clients, or the query parameters.
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); len(key) != 0 {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if _, password, ok := c.Request.BasicAuth(); ok && len(password) != 0 {
+		return password
+	}
+	for _, query := range APIKeyQueries {
+		if key := c.Query(query); len(key) != 0 {
+			return key
+		}
+	}
+	return ""
+}
+
+// rateLimited aborts the request with 429 Too Many Requests.
+func rateLimited(c *gin.Context, limiters *rateLimiters) {
+	retryAfter := int(math.Ceil(1 / float64(limiters.limit)))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": errors.ErrRateLimited.Error()})
+}
+
+// rateLimiters holds the token buckets of the clients.
+type rateLimiters struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	limiters  map[string]*rate.Limiter
+	lastPurge time.Time
+}
+
+// newRateLimiters creates the token buckets that refill limit tokens per second, up to burst tokens.
+// The burst defaults to the limit rounded up. It returns nil if limit is not positive.
+func newRateLimiters(limit float64, burst int) *rateLimiters {
+	if limit <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(limit))
+	}
+	return &rateLimiters{
+		limit:     rate.Limit(limit),
+		burst:     burst,
+		limiters:  make(map[string]*rate.Limiter),
+		lastPurge: time.Now(),
+	}
+}
+
+// allow reports whether a request of the client is allowed. A nil rateLimiters allows every request.
+func (r *rateLimiters) allow(client string) bool {
+	if r == nil {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastPurge) > RateLimitPurgeInterval {
+		// full buckets are the same as new ones
+		for k, limiter := range r.limiters {
+			if limiter.TokensAt(now) >= float64(r.burst) {
+				delete(r.limiters, k)
+			}
+		}
+		r.lastPurge = now
+	}
+
+	limiter, ok := r.limiters[client]
+	if !ok {
+		limiter = rate.NewLimiter(r.limit, r.burst)
+		r.limiters[client] = limiter
+	}
+	return limiter.AllowN(now, 1)
+}
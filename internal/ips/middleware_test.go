@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestAccessMiddlewares(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := &Manager{Conf: &Config{
+		APIKeys:         []string{"k1"},
+		RateLimitPerKey: 1,
+		RateLimitPerIP:  1,
+		RateLimitBurst:  2,
+		AllowIPs:        []string{"10.0.0.0/8"},
+	}}
+	allowlist, err := m.AllowlistMiddleware()
+	ast.Nil(err)
+
+	router := gin.New()
+	router.Use(allowlist, m.RateLimitMiddleware(), m.APIKeyMiddleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(remote string, header map[string]string, query string) int {
+		req := httptest.NewRequest(http.MethodGet, "/"+query, nil)
+		req.RemoteAddr = remote
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// allowlist
+	ast.Equal(http.StatusForbidden, do("1.1.1.1:1234", map[string]string{APIKeyHeader: "k1"}, ""))
+
+	// api key
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.1:1234", nil, ""))
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.2:1234", map[string]string{APIKeyHeader: "k2"}, ""))
+
+	// per key, the bucket of k1 is shared by the clients
+	ast.Equal(http.StatusOK, do("10.0.0.3:1234", map[string]string{APIKeyHeader: "k1"}, ""))
+	ast.Equal(http.StatusOK, do("10.0.0.4:1234", map[string]string{"Authorization": "Bearer k1"}, ""))
+	ast.Equal(http.StatusTooManyRequests, do("10.0.0.5:1234", nil, "?key=k1"))
+
+	// unknown keys have no bucket and are limited per client IP only
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.6:1234", map[string]string{APIKeyHeader: "k3"}, ""))
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.7:1234", map[string]string{APIKeyHeader: "k3"}, ""))
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.8:1234", map[string]string{APIKeyHeader: "k3"}, ""))
+
+	// per client IP, 10.0.0.1 and 10.0.0.2 have used a token each
+	ast.Equal(http.StatusUnauthorized, do("10.0.0.1:1234", nil, ""))
+	ast.Equal(http.StatusTooManyRequests, do("10.0.0.1:1234", nil, ""))
+
+	// disabled
+	m = &Manager{Conf: &Config{}}
+	allowlist, err = m.AllowlistMiddleware()
+	ast.Nil(err)
+	ast.Nil(allowlist)
+	ast.Nil(m.RateLimitMiddleware())
+	ast.Nil(m.APIKeyMiddleware())
+	cors, err := m.CORSMiddleware()
+	ast.Nil(err)
+	ast.Nil(cors)
+
+	m.Conf.AllowIPs = []string{"10.0.0.0/33"}
+	_, err = m.AllowlistMiddleware()
+	ast.NotNil(err)
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := &Manager{Conf: &Config{CORSAllowOrigins: []string{"https://ips.test", "https://*.example.org"}}}
+	cors, err := m.CORSMiddleware()
+	ast.Nil(err)
+	ast.NotNil(cors)
+
+	router := gin.New()
+	router.Use(cors)
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	do := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := do("https://ips.test")
+	ast.Equal(http.StatusOK, w.Code)
+	ast.Equal("https://ips.test", w.Header().Get("Access-Control-Allow-Origin"))
+	w = do("https://api.example.org")
+	ast.Equal("https://api.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+	ast.Equal(http.StatusForbidden, do("https://evil.com").Code)
+
+	m.Conf.CORSAllowOrigins = []string{"*"}
+	cors, err = m.CORSMiddleware()
+	ast.Nil(err)
+	ast.NotNil(cors)
+
+	// invalid origins are reported instead of panicking
+	for _, origins := range [][]string{{"example.com"}, {"https://example.com", "ftp.example.com"}, {"https://*.*.example.com"}} {
+		m.Conf.CORSAllowOrigins = origins
+		ast.NotPanics(func() {
+			cors, err = m.CORSMiddleware()
+		})
+		ast.Equal(errors.ErrInvalidOrigin, err, origins)
+		ast.Nil(cors)
+	}
+}
+
+func TestGeoIP2Authentication(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	do := func(m *Manager, account, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/geoip/v2.1/city/1.1.1.1", nil)
+		if len(account) != 0 {
+			req.SetBasicAuth(account, key)
+		}
+		w := httptest.NewRecorder()
+		m.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the API keys protect the GeoIP2 API without the GeoIP2 accounts
+	m := &Manager{Conf: &Config{APIKeys: []string{"k1"}}, router: gin.New()}
+	m.InitRouter()
+	ast.Equal(http.StatusUnauthorized, do(m, "", ""))
+	ast.Equal(http.StatusUnauthorized, do(m, "42", "k2"))
+	ast.NotEqual(http.StatusUnauthorized, do(m, "42", "k1"))
+
+	m = &Manager{Conf: &Config{APIKeys: []string{"k1"}, GeoIP2Accounts: map[string]string{"42": "license"}}, router: gin.New()}
+	m.InitRouter()
+	ast.Equal(http.StatusUnauthorized, do(m, "42", "k1"))
+	ast.NotEqual(http.StatusUnauthorized, do(m, "42", "license"))
+}
+
+func TestRateLimitScope(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := &Manager{Conf: &Config{APIKeys: []string{"k1"}, RateLimitPerKey: 1, RateLimitBurst: 1}, router: gin.New()}
+	m.InitRouter()
+	do := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", "k1")
+		w := httptest.NewRecorder()
+		m.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the APIs share the limit, the probes are not limited
+	ast.NotEqual(http.StatusTooManyRequests, do("/ipinfo/1.1.1.1"))
+	ast.Equal(http.StatusTooManyRequests, do("/api/v1/ip?ip=1.1.1.1"))
+	ast.Equal(http.StatusTooManyRequests, do("/geoip/v2.1/city/1.1.1.1"))
+	ast.NotEqual(http.StatusTooManyRequests, do("/healthz"))
+	ast.NotEqual(http.StatusTooManyRequests, do("/readyz"))
+}
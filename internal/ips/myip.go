@@ -29,5 +29,9 @@ func (m *Manager) MyIP() (string, error) {
 		return "", err
 	}
 
-	return m.ParseText(ip.String())
+	ret, err := m.ParseText(ip.String())
+	if err != nil {
+		return "", err
+	}
+	return ret + m.FlushOutput(), nil
 }
@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIVersion is the version of the API described by the OpenAPI specification.
+const OpenAPIVersion = "1.0.0"
+
+// openAPIOperation documents a route of the router.
+// Path parameters are derived from the route, only the query parameters are listed.
+type openAPIOperation struct {
+	Summary     string
+	Tag         string
+	Query       []openAPIParameter
+	RequestBody string // content type of the request body, none if empty
+	Response    string // content type of the response, JSON if empty
+	Schema      string // schema of the JSON response, a free-form object if empty
+	Security    string // security scheme
+}
+
+// openAPIParameter documents a query parameter.
+type openAPIParameter struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Security schemes of the OpenAPI specification.
+const (
+	openAPISecurityAPIKey = "apiKey"
+	openAPISecurityBasic  = "basicAuth"
+)
+
+// openAPIProfileParameters documents the query parameters selecting the profile of a lookup.
+var openAPIProfileParameters = []openAPIParameter{
+	{Name: "profile", Description: "Name of the profile"},
+	{Name: "fields", Description: "Fields to output, separated by commas"},
+	{Name: "lang", Description: "Language of the output"},
+	{Name: "db_fields", Description: "Output the database fields, true or false"},
+}
+
+// openAPIOperations documents the routes registered by InitRouter, keyed by "<method> <path>".
+var openAPIOperations = map[string]openAPIOperation{
+	"GET /healthz": {Summary: "Liveness probe", Tag: "health"},
+	"GET /readyz":  {Summary: "Readiness probe, ready once the configured databases are loaded", Tag: "health"},
+	"GET /api/v1/ip": {
+		Summary:  "Look up an IP address, or the client IP without the parameter",
+		Tag:      "api",
+		Query:    append([]openAPIParameter{{Name: "ip", Description: "IP address"}}, openAPIProfileParameters...),
+		Schema:   "IPInfo",
+		Security: openAPISecurityAPIKey,
+	},
+	"GET /api/v1/myip": {
+		Summary:  "Look up the client IP",
+		Tag:      "api",
+		Query:    openAPIProfileParameters,
+		Schema:   "IPInfo",
+		Security: openAPISecurityAPIKey,
+	},
+	"GET /api/v1/query": {
+		Summary:  "Parse the text and look up the IP addresses and domains in it",
+		Tag:      "api",
+		Query:    append([]openAPIParameter{{Name: "text", Description: "Text to parse", Required: true}}, openAPIProfileParameters...),
+		Security: openAPISecurityAPIKey,
+	},
+	"GET /api/v1/versions": {Summary: "Versions of the loaded databases", Tag: "api", Security: openAPISecurityAPIKey},
+	"GET /api/v1/meta":     {Summary: "Metadata and versions of the loaded databases", Tag: "api", Security: openAPISecurityAPIKey},
+	"POST /api/v1/batch": {
+		Summary:     "Look up a JSON array or an NDJSON stream of IP addresses",
+		Tag:         "api",
+		Query:       openAPIProfileParameters,
+		RequestBody: "application/json",
+		Security:    openAPISecurityAPIKey,
+	},
+	"GET /geoip/v2.1/:endpoint/:ip": {
+		Summary:  "GeoIP2 web service compatible lookup, the endpoint is country, city or insights",
+		Tag:      "geoip2",
+		Security: openAPISecurityBasic,
+	},
+	"GET /ipinfo":     {Summary: "ipinfo.io compatible lookup of the client IP", Tag: "ipinfo", Schema: "IPInfoIO", Security: openAPISecurityAPIKey},
+	"GET /ipinfo/:ip": {Summary: "ipinfo.io compatible lookup", Tag: "ipinfo", Schema: "IPInfoIO", Security: openAPISecurityAPIKey},
+	"GET /ipinfo/:ip/:field": {
+		Summary:  "ipinfo.io compatible lookup of a single field",
+		Tag:      "ipinfo",
+		Response: "text/plain",
+		Security: openAPISecurityAPIKey,
+	},
+	"POST /ipinfo/batch": {
+		Summary:     "ipinfo.io compatible batch lookup",
+		Tag:         "ipinfo",
+		RequestBody: "application/json",
+		Security:    openAPISecurityAPIKey,
+	},
+	"GET /metrics":      {Summary: "Prometheus metrics", Tag: "health", Response: "text/plain"},
+	"GET /openapi.json": {Summary: "OpenAPI specification", Tag: "health"},
+}
+
+// openAPIIgnoredPaths lists the routes of the web page, which are not part of the API.
+var openAPIIgnoredPaths = map[string]bool{
+	"/":                 true,
+	"/favicon.ico":      true,
+	"/static/*filepath": true,
+}
+
+// openAPISchemas lists the schemas of the JSON responses.
+var openAPISchemas = map[string]interface{}{
+	"IPInfo": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"ip":   gin.H{"type": "string"},
+			"net":  gin.H{"type": "string"},
+			"data": gin.H{"type": "object", "additionalProperties": gin.H{"type": "string"}},
+		},
+	},
+	"IPInfoIO": gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"ip":       gin.H{"type": "string"},
+			"city":     gin.H{"type": "string"},
+			"region":   gin.H{"type": "string"},
+			"country":  gin.H{"type": "string"},
+			"loc":      gin.H{"type": "string"},
+			"org":      gin.H{"type": "string"},
+			"postal":   gin.H{"type": "string"},
+			"timezone": gin.H{"type": "string"},
+		},
+	},
+	"Error": gin.H{
+		"type":       "object",
+		"properties": gin.H{"error": gin.H{"type": "string"}},
+	},
+}
+
+// GetOpenAPI handles the GET /openapi.json endpoint. It returns the OpenAPI specification
+// of the routes registered in the router.
+func (m *Manager) GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, m.OpenAPISpec(m.router.Routes()))
+}
+
+// OpenAPISpec builds the OpenAPI 3 specification of the routes.
+// Routes without documentation are described by their handlers.
+func (m *Manager) OpenAPISpec(routes gin.RoutesInfo) map[string]interface{} {
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path || routes[i].Path == routes[j].Path && routes[i].Method < routes[j].Method
+	})
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range routes {
+		if route.Method == http.MethodHead || openAPIIgnoredPaths[route.Path] {
+			continue
+		}
+		op, ok := openAPIOperations[route.Method+" "+route.Path]
+		if !ok {
+			op = openAPIOperation{Summary: route.Handler}
+		}
+
+		path, params := openAPIPath(route.Path)
+		for _, query := range op.Query {
+			params = append(params, gin.H{
+				"name":        query.Name,
+				"in":          "query",
+				"required":    query.Required,
+				"description": query.Description,
+				"schema":      gin.H{"type": "string"},
+			})
+		}
+
+		operation := gin.H{
+			"summary":   op.Summary,
+			"responses": m.openAPIResponses(op),
+		}
+		if len(op.Tag) != 0 {
+			operation["tags"] = []string{op.Tag}
+		}
+		if len(params) != 0 {
+			operation["parameters"] = params
+		}
+		if len(op.RequestBody) != 0 {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content":  gin.H{op.RequestBody: gin.H{"schema": gin.H{}}},
+			}
+		}
+		if security := m.openAPISecurity(op.Security); len(security) != 0 {
+			operation["security"] = []gin.H{{security: []string{}}}
+		}
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "IPS",
+			"version": OpenAPIVersion,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": openAPISchemas,
+			"securitySchemes": gin.H{
+				openAPISecurityAPIKey: gin.H{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+				openAPISecurityBasic:  gin.H{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// openAPISecurity returns the security scheme if it is enabled by the configuration.
+// The GeoIP2 API falls back to the API keys without the GeoIP2 accounts.
+func (m *Manager) openAPISecurity(security string) string {
+	switch {
+	case security == openAPISecurityAPIKey && len(m.Conf.APIKeys) != 0:
+		return security
+	case security == openAPISecurityBasic && len(m.Conf.GeoIP2Accounts) != 0:
+		return security
+	case security == openAPISecurityBasic && len(m.Conf.APIKeys) != 0:
+		return openAPISecurityAPIKey
+	}
+	return ""
+}
+
+// openAPIResponses returns the responses of the operation.
+func (m *Manager) openAPIResponses(op openAPIOperation) gin.H {
+	content := gin.H{"text/plain": gin.H{"schema": gin.H{"type": "string"}}}
+	if len(op.Response) == 0 {
+		schema := gin.H{"type": "object"}
+		if len(op.Schema) != 0 {
+			schema = gin.H{"$ref": "#/components/schemas/" + op.Schema}
+		}
+		content = gin.H{"application/json": gin.H{"schema": schema}}
+	}
+
+	errorContent := gin.H{"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/Error"}}}
+	return gin.H{
+		"200":     gin.H{"description": "OK", "content": content},
+		"default": gin.H{"description": "Error", "content": errorContent},
+	}
+}
+
+// openAPIPath converts a route path to an OpenAPI path, with its path parameters.
+// e.g. /ipinfo/:ip/:field -> /ipinfo/{ip}/{field}
+func openAPIPath(path string) (string, []gin.H) {
+	params := make([]gin.H, 0)
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+		params = append(params, gin.H{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   gin.H{"type": "string"},
+		})
+	}
+	return strings.Join(segments, "/"), params
+}
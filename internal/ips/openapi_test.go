@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := &Manager{Conf: &Config{Metrics: true, APIKeys: []string{"k1"}}, router: gin.New()}
+	m.InitRouter()
+
+	// every route of the API is documented
+	routes := m.router.Routes()
+	for _, route := range routes {
+		if route.Method == http.MethodHead || openAPIIgnoredPaths[route.Path] {
+			continue
+		}
+		_, ok := openAPIOperations[route.Method+" "+route.Path]
+		ast.True(ok, "undocumented route %s %s", route.Method, route.Path)
+	}
+
+	spec := m.OpenAPISpec(routes)
+	paths := spec["paths"].(map[string]map[string]interface{})
+	ast.NotContains(paths, "/")
+	ast.Contains(paths, "/healthz")
+	ast.Contains(paths["/api/v1/batch"], "post")
+
+	field := paths["/ipinfo/{ip}/{field}"]["get"].(gin.H)
+	ast.Len(field["parameters"], 2)
+	ast.Equal([]gin.H{{openAPISecurityAPIKey: []string{}}}, field["security"])
+	ast.Equal([]gin.H{{openAPISecurityAPIKey: []string{}}}, paths["/geoip/v2.1/{endpoint}/{ip}"]["get"].(gin.H)["security"])
+
+	m.Conf.APIKeys = nil
+	paths = m.OpenAPISpec(routes)["paths"].(map[string]map[string]interface{})
+	ast.NotContains(paths["/geoip/v2.1/{endpoint}/{ip}"]["get"], "security")
+}
+
+func TestReadyz(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	m := &Manager{Conf: &Config{IPv4File: []string{"missing.ipdb"}}}
+	router := gin.New()
+	router.GET("/readyz", m.Readyz)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	ast.Equal(http.StatusServiceUnavailable, w.Code)
+	ast.JSONEq(`{"status": "unavailable", "ipv4": "loading", "ipv6": "disabled"}`, w.Body.String())
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// outputState keeps the output across the calls of ParseText, such as the header of CSV
+// and the rows of a table, so that the results of a pipeline are output as a whole.
+type outputState struct {
+	mu      sync.Mutex
+	started bool
+	header  []string
+	rows    [][]string
+}
+
+// serializeDelimited serializes the IP information into CSV or TSV rows, by the comma.
+// The header row of the fields is output before the first row, and the later rows follow its columns.
+// Domains and texts are skipped.
+func (m *Manager) serializeDelimited(data []interface{}, comma rune) (string, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Comma = comma
+
+	m.output.mu.Lock()
+	defer m.output.mu.Unlock()
+	for _, info := range data {
+		v, ok := info.(*model.IPInfo)
+		if !ok {
+			continue
+		}
+		if !m.output.started {
+			m.output.started = true
+			m.output.header = m.outputHeader(v)
+			if err := w.Write(m.output.header); err != nil {
+				log.Debug("csv.Writer.Write error: ", err)
+				return "", err
+			}
+		}
+		if err := w.Write(m.outputRow(v, m.output.header)); err != nil {
+			log.Debug("csv.Writer.Write error: ", err)
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Debug("csv.Writer.Flush error: ", err)
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// serializeNDJSON serializes the IP information and domains into one JSON object per line.
+// Texts are skipped.
+func (m *Manager) serializeNDJSON(data []interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, info := range data {
+		var item interface{}
+		switch v := info.(type) {
+		case *model.IPInfo:
+			item = v.Output(m.Conf.UseDBFields)
+		case *model.DomainInfo:
+			item = v
+		default:
+			continue
+		}
+		if err := encoder.Encode(item); err != nil {
+			log.Debug("json.Encoder.Encode error: ", err)
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// serializeYAML serializes the IP information and domains into a YAML document, as the JSON output.
+// The documents of a pipeline are separated by "---".
+func (m *Manager) serializeYAML(data []interface{}) (string, error) {
+	list := &model.DataList{}
+	for _, info := range data {
+		switch v := info.(type) {
+		case *model.IPInfo:
+			list.AddItem(v.Output(m.Conf.UseDBFields))
+		case *model.DomainInfo:
+			list.AddDomain(v)
+		}
+	}
+	if len(list.Items) == 0 && len(list.Domains) == 0 {
+		return "", nil
+	}
+
+	ret, err := yaml.Marshal(list)
+	if err != nil {
+		log.Debug("yaml.Marshal error: ", err)
+		return "", err
+	}
+
+	m.output.mu.Lock()
+	defer m.output.mu.Unlock()
+	if m.output.started {
+		return "---\n" + string(ret), nil
+	}
+	m.output.started = true
+	return string(ret), nil
+}
+
+// appendTableRows adds the IP information to the rows of the table, which is rendered by FlushOutput.
+// Domains and texts are skipped.
+func (m *Manager) appendTableRows(data []interface{}) {
+	m.output.mu.Lock()
+	defer m.output.mu.Unlock()
+	for _, info := range data {
+		v, ok := info.(*model.IPInfo)
+		if !ok {
+			continue
+		}
+		if !m.output.started {
+			m.output.started = true
+			m.output.header = m.outputHeader(v)
+		}
+		m.output.rows = append(m.output.rows, m.outputRow(v, m.output.header))
+	}
+}
+
+// FlushOutput returns the output held until all the results are parsed, i.e. the table,
+// and resets the output state. It returns an empty string for the other output types.
+func (m *Manager) FlushOutput() string {
+	m.output.mu.Lock()
+	defer m.output.mu.Unlock()
+
+	ret := ""
+	if m.Conf.OutputType == OutputTypeTable && len(m.output.rows) > 0 {
+		writer := &strings.Builder{}
+		table := tablewriter.NewWriter(writer)
+		table.SetHeader(m.output.header)
+		table.SetAutoFormatHeaders(false)
+		table.SetAutoWrapText(false)
+		table.AppendBulk(m.output.rows)
+		table.Render()
+		ret = writer.String()
+	}
+
+	m.output.started = false
+	m.output.header = nil
+	m.output.rows = nil
+	return ret
+}
+
+// outputHeader returns the columns of the IP information, the IP and the network followed by the fields.
+func (m *Manager) outputHeader(info *model.IPInfo) []string {
+	return append([]string{"ip", "net"}, info.OutputFields(m.Conf.UseDBFields)...)
+}
+
+// outputRow returns the values of the IP information in the columns of the header.
+// The columns missing in the IP information are empty.
+func (m *Manager) outputRow(info *model.IPInfo, header []string) []string {
+	output := info.Output(m.Conf.UseDBFields)
+	row := make([]string, len(header))
+	for i, column := range header {
+		switch {
+		case i == 0:
+			row[i] = output.IP
+		case i == 1:
+			row[i] = output.Net
+		default:
+			row[i] = output.Data[column]
+		}
+	}
+	return row
+}
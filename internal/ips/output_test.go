@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+
+	m.Conf.OutputType = OutputTypeCSV
+	ret, err := m.ParseText("200.1.1.1 example.com")
+	ast.Nil(err)
+	ast.Equal("ip,net,country,city\n200.1.1.1,192.0.0.0/3,中国,深圳\n", ret)
+	ret, err = m.ParseText("1.1.1.1")
+	ast.Nil(err)
+	ast.Equal("1.1.1.1,0.0.0.0/1,保留,\n", ret)
+	ast.Equal("", m.FlushOutput())
+
+	m.Conf.OutputType = OutputTypeTSV
+	ret, err = m.ParseText("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("ip\tnet\tcountry\tcity\n200.1.1.1\t192.0.0.0/3\t中国\t深圳\n", ret)
+	m.FlushOutput()
+
+	m.Conf.OutputType = OutputTypeNDJSON
+	ret, err = m.ParseText("200.1.1.1 1.1.1.1 example.com")
+	ast.Nil(err)
+	ast.Equal(`{"ip":"200.1.1.1","net":"192.0.0.0/3","data":{"city":"深圳","country":"中国"}}
+{"ip":"1.1.1.1","net":"0.0.0.0/1","data":{"city":"","country":"保留"}}
+{"domain":"example.com","main_domain":"","data":null}
+`, ret)
+
+	m.Conf.OutputType = OutputTypeYAML
+	ret, err = m.ParseText("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("items:\n    - ip: 200.1.1.1\n      net: 192.0.0.0/3\n      data:\n        city: 深圳\n        country: 中国\n", ret)
+	ret, err = m.ParseText("200.1.1.1")
+	ast.Nil(err)
+	ast.Contains(ret, "---\n")
+	m.FlushOutput()
+
+	m.Conf.OutputType = OutputTypeTable
+	ret, err = m.ParseText("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("", ret)
+	_, err = m.ParseText("1.1.1.1")
+	ast.Nil(err)
+	ast.Equal(`+-----------+-------------+---------+------+
+|    ip     |     net     | country | city |
++-----------+-------------+---------+------+
+| 200.1.1.1 | 192.0.0.0/3 | 中国    | 深圳 |
+| 1.1.1.1   | 0.0.0.0/1   | 保留    |      |
++-----------+-------------+---------+------+
+`, m.FlushOutput())
+	ast.Equal("", m.FlushOutput())
+
+	// unknown output types fall back to text
+	m.Conf.OutputType = OutputTypeText
+	m.Conf.TextFormat = "%origin [%values]"
+	text, err := m.ParseText("1.1.1.1")
+	ast.Nil(err)
+	m.Conf.OutputType = "unknown"
+	ret, err = m.ParseText("1.1.1.1")
+	ast.Nil(err)
+	ast.Equal(text, ret)
+	ast.Equal("1.1.1.1 [保留]", ret)
+}
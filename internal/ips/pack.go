@@ -17,15 +17,19 @@
 package ips
 
 import (
+	"io"
 	"net/url"
 	"os"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/format"
 	"github.com/sjzar/ips/format/mmdb"
 	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/format/ruleset"
 	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/util"
 	"github.com/sjzar/ips/pkg/errors"
 )
 
@@ -52,20 +56,55 @@ func (m *Manager) Pack(_format, file []string, _outputFormat, outputFile string)
 	}
 
 	// Setup output destination
+	// The output file is written to a temporary file first, and replaces the
+	// previous file only after the new one has been completely written and validated.
 	output := os.Stdout
+	var atomicFile *util.AtomicFile
 	if len(outputFile) != 0 {
 		var err error
-		output, err = os.Create(outputFile)
+		atomicFile, err = util.CreateAtomicFile(outputFile)
 		if err != nil {
-			log.Debug("os.Create error: ", err)
+			log.Debug("util.CreateAtomicFile error: ", err)
 			return err
 		}
 		defer func() {
-			_ = output.Close()
+			_ = atomicFile.Abort()
 		}()
+		output = atomicFile.File
 	}
 
-	// Add specific logic based on the writer type
+	if err := m.setWriterOption(writer, output); err != nil {
+		return err
+	}
+
+	// Dump data using the dumper
+	dumper := ipio.NewStandardDumper(reader, writer)
+	if err := dumper.Dump(m.Conf.ReaderJobs); err != nil {
+		log.Debug("dumper.Dump error: ", err)
+		return err
+	}
+
+	// Write to the output destination
+	if _, err := dumper.WriteTo(output); err != nil {
+		log.Debug("dumper.WriteTo error: ", err)
+		return err
+	}
+
+	if atomicFile != nil {
+		if err := atomicFile.Commit(func(name string) error {
+			return validateDatabase(_outputFormat, name)
+		}); err != nil {
+			log.Debug("atomicFile.Commit error: ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setWriterOption sets the writer options of the configuration, based on the writer type.
+// The plain writer outputs to the output immediately, if not nil.
+func (m *Manager) setWriterOption(writer format.Writer, output io.Writer) error {
 	switch writer.(type) {
 	case *mmdb.Writer:
 		writerOptionArg, err := url.ParseQuery(m.Conf.WriterOption)
@@ -80,25 +119,32 @@ func (m *Manager) Pack(_format, file []string, _outputFormat, outputFile string)
 			log.Debug("writer.SetOption error: ", err)
 			return err
 		}
+	case *ruleset.Writer:
+		writerOptionArg, err := url.ParseQuery(m.Conf.WriterOption)
+		if err != nil {
+			log.Debug("url.ParseQuery error: ", err)
+			return err
+		}
+		option := ruleset.WriterOption{
+			GroupBy: writerOptionArg.Get("group_by"),
+			Name:    writerOptionArg.Get("name"),
+			Policy:  writerOptionArg.Get("policy"),
+		}
+		if groups := writerOptionArg.Get("groups"); len(groups) != 0 {
+			option.Groups = strings.Split(groups, ",")
+		}
+		if err := writer.SetOption(option); err != nil {
+			log.Debug("writer.SetOption error: ", err)
+			return err
+		}
 	case *plain.Writer:
+		if output == nil {
+			return nil
+		}
 		if err := writer.SetOption(plain.WriterOption{IW: output}); err != nil {
 			log.Debug("writer.SetOption error: ", err)
 			return err
 		}
 	}
-
-	// Dump data using the dumper
-	dumper := ipio.NewStandardDumper(reader, writer)
-	if err := dumper.Dump(m.Conf.ReaderJobs); err != nil {
-		log.Debug("dumper.Dump error: ", err)
-		return err
-	}
-
-	// Write to the output destination
-	if _, err := dumper.WriteTo(output); err != nil {
-		log.Debug("dumper.WriteTo error: ", err)
-		return err
-	}
-
 	return nil
 }
@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format/geo"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// MaxProfiles is the maximum number of profiles cached with their readers.
+// The earliest cached profile is evicted and its readers are closed when exceeded.
+const MaxProfiles = 32
+
+// Profile overrides the database and output options of the service for the requests selecting it.
+// Options left empty are inherited from the configuration.
+type Profile struct {
+	// IPv4File and IPv4Format specify the IPv4 database files and their formats.
+	IPv4File   []string `mapstructure:"ipv4_file"`
+	IPv4Format []string `mapstructure:"ipv4_format"`
+
+	// IPv6File and IPv6Format specify the IPv6 database files and their formats.
+	IPv6File   []string `mapstructure:"ipv6_file"`
+	IPv6Format []string `mapstructure:"ipv6_format"`
+
+	// HybridMode specifies the operational mode of the HybridReader.
+	HybridMode string `mapstructure:"hybrid_mode"`
+
+	// Fields specifies the fields to output.
+	Fields string `mapstructure:"fields"`
+
+	// UseDBFields indicates whether to use database fields.
+	UseDBFields bool `mapstructure:"use_db_fields"`
+
+	// RewriteFiles specifies the files for data rewriting.
+	RewriteFiles string `mapstructure:"rewrite_files"`
+
+	// Lang specifies the language for the output.
+	Lang string `mapstructure:"lang"`
+}
+
+// profileCache caches the managers of the profiles, each with its own readers.
+type profileCache struct {
+	mu       sync.Mutex
+	managers map[string]*Manager
+	order    []string
+}
+
+// get returns the cached manager of the key, or creates it by create.
+func (p *profileCache) get(key string, create func() *Manager) *Manager {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pm, ok := p.managers[key]; ok {
+		return pm
+	}
+
+	pm := create()
+	if p.managers == nil {
+		p.managers = make(map[string]*Manager)
+	}
+	if len(p.order) >= MaxProfiles {
+		p.managers[p.order[0]].evict()
+		delete(p.managers, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.managers[key] = pm
+	p.order = append(p.order, key)
+	return pm
+}
+
+// list returns the cached managers.
+func (p *profileCache) list() []*Manager {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := make([]*Manager, 0, len(p.managers))
+	for _, key := range p.order {
+		ret = append(ret, p.managers[key])
+	}
+	return ret
+}
+
+// profileLookup looks up the IPs of a request with the readers of the selected profile.
+// The fields and the language overridden by the request are applied to the results of the readers,
+// so that the overrides share the loaded databases.
+type profileLookup struct {
+	m          *Manager
+	fields     string
+	translator *operate.Translator
+}
+
+// parseSegment processes the provided segment like Manager.parseSegment, with the overrides applied.
+func (l *profileLookup) parseSegment(segment parser.Segment) (interface{}, error) {
+	switch segment.Type {
+	case parser.TextTypeIPv4, parser.TextTypeIPv6:
+		return l.parseIP(segment.Content)
+	}
+	return l.m.parseSegment(segment)
+}
+
+// parseIP finds the information of the IP like Manager.parseIP, with the overrides applied.
+func (l *profileLookup) parseIP(content string) (*model.IPInfo, error) {
+	info, err := l.m.parseIP(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(l.fields) != 0 {
+		// the readers keep every field of the database in the data, only the output fields are selected
+		fs, err := operate.NewFieldSelector(&model.Meta{Fields: info.Fields, FieldAlias: info.FieldAlias}, l.fields)
+		if err != nil {
+			log.Debug("operate.NewFieldSelector error: ", err)
+			return nil, err
+		}
+		if err := fs.Do(info); err != nil {
+			return nil, err
+		}
+	}
+	if l.translator != nil {
+		if err := l.translator.Do(info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// requestProfile returns the lookup of the profile selected by the request, and whether to output
+// the database fields. The profile is selected by the "profile" query parameter, and overridden by
+// the "fields", "lang" and "db_fields" query parameters.
+// Only the profiles load their own readers, the overrides are applied per request.
+func (m *Manager) requestProfile(c *gin.Context) (*profileLookup, bool, error) {
+	name, fields, lang := c.Query("profile"), c.Query("fields"), c.Query("lang")
+
+	pm := m
+	if len(name) != 0 {
+		profile, ok := m.Conf.Profiles[name]
+		if !ok || profile == nil {
+			return nil, false, errors.ErrProfileNotFound
+		}
+		pm = m.profiles.get(name, func() *Manager {
+			return m.newProfileManager(name, profile)
+		})
+	}
+
+	lookup := &profileLookup{m: pm, fields: fields}
+	if len(lang) != 0 {
+		if err := geo.CheckLanguage(lang); err != nil {
+			return nil, false, err
+		}
+		// the data has been translated to the language of the profile, if any
+		lookup.translator = &operate.Translator{SourceLang: pm.Conf.Lang, TargetLang: lang}
+	}
+
+	useDBFields := pm.Conf.UseDBFields
+	if dbFields := c.Query("db_fields"); len(dbFields) != 0 {
+		useDBFields, _ = strconv.ParseBool(dbFields)
+	}
+	return lookup, useDBFields, nil
+}
+
+// newProfileManager creates a manager with the configuration overridden by the profile.
+// The readers of the manager are loaded lazily on the first lookup.
+func (m *Manager) newProfileManager(name string, profile *Profile) *Manager {
+	conf := *m.Conf
+	if len(profile.IPv4File) != 0 {
+		conf.IPv4File, conf.IPv4Format = profile.IPv4File, profileFormat(profile.IPv4Format, profile.IPv4File)
+	}
+	if len(profile.IPv6File) != 0 {
+		conf.IPv6File, conf.IPv6Format = profile.IPv6File, profileFormat(profile.IPv6Format, profile.IPv6File)
+	}
+	if len(profile.HybridMode) != 0 {
+		conf.HybridMode = profile.HybridMode
+	}
+	if len(profile.Fields) != 0 {
+		conf.Fields = profile.Fields
+	}
+	if profile.UseDBFields {
+		conf.UseDBFields = profile.UseDBFields
+	}
+	if len(profile.RewriteFiles) != 0 {
+		conf.RewriteFiles = profile.RewriteFiles
+	}
+	if len(profile.Lang) != 0 {
+		conf.Lang = profile.Lang
+	}
+
+	return &Manager{
+		Conf:     &conf,
+		clientIP: m.clientIP,
+		profile:  name,
+	}
+}
+
+// profileFormat returns the formats of the profile files, detected automatically if not specified.
+func profileFormat(formats, files []string) []string {
+	if len(formats) != len(files) {
+		return make([]string, len(files))
+	}
+	return formats
+}
+
+// readerName returns the name of the reader of the IP version, prefixed by the profile.
+func (m *Manager) readerName(ipVersion string) string {
+	if len(m.profile) == 0 {
+		return ipVersion
+	}
+	return m.profile + "/" + ipVersion
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+func TestRequestProfile(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	m.Conf.Profiles = map[string]*Profile{
+		"isp": {IPv4File: []string{"b.awdb"}, Fields: "isp", UseDBFields: true},
+		"en":  {Fields: "country,province", Lang: "en"},
+	}
+
+	request := func(query string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/ip?"+query, nil)
+		return c
+	}
+
+	lookup, useDBFields, err := m.requestProfile(request("ip=1.1.1.1"))
+	ast.Nil(err)
+	ast.Same(m, lookup.m)
+	ast.False(useDBFields)
+
+	lookup, useDBFields, err = m.requestProfile(request("profile=isp"))
+	ast.Nil(err)
+	ast.True(useDBFields)
+	pm := lookup.m
+	ast.Equal([]string{"b.awdb"}, pm.Conf.IPv4File)
+	ast.Equal([]string{""}, pm.Conf.IPv4Format)
+	ast.Equal("isp", pm.Conf.Fields)
+	ast.Equal("isp/ipv4", pm.readerName("ipv4"))
+
+	lookup, useDBFields, err = m.requestProfile(request("profile=isp&db_fields=false"))
+	ast.Nil(err)
+	ast.False(useDBFields)
+	ast.Same(pm, lookup.m)
+
+	// the overrides are applied to the shared readers
+	lookup, _, err = m.requestProfile(request("fields=province,isp&lang=en"))
+	ast.Nil(err)
+	ast.Same(m, lookup.m)
+	info, err := lookup.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal(map[string]string{"province": "Guangdong", "isp": "电信"}, info.Output(false).Data)
+
+	lookup, _, err = m.requestProfile(request("fields=country"))
+	ast.Nil(err)
+	segment, err := lookup.parseSegment(parser.Segment{Type: parser.TextTypeIPv4, Content: "200.1.1.1"})
+	ast.Nil(err)
+	ast.Equal(map[string]string{"country": "中国"}, segment.(*model.IPInfo).Output(false).Data)
+
+	info, err = m.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal(map[string]string{"country": "中国", "city": "深圳"}, info.Output(false).Data)
+	ast.Len(m.profiles.list(), 1)
+
+	// the data of a profile has been translated to its language
+	lookup, _, err = m.requestProfile(request("profile=en&lang=zh-CN"))
+	ast.Nil(err)
+	info, err = lookup.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal(map[string]string{"country": "中国", "province": "广东"}, info.Output(false).Data)
+	lookup, _, err = m.requestProfile(request("profile=en"))
+	ast.Nil(err)
+	info, err = lookup.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal(map[string]string{"country": "China", "province": "Guangdong"}, info.Output(false).Data)
+	ast.Len(m.profiles.list(), 2)
+
+	_, _, err = m.requestProfile(request("profile=unknown"))
+	ast.Equal(errors.ErrProfileNotFound, err)
+
+	_, _, err = m.requestProfile(request("lang=unknown"))
+	ast.NotNil(err)
+}
+
+func TestProfileCache(t *testing.T) {
+	ast := assert.New(t)
+
+	m := NewManager(&Config{})
+	for i := 0; i < MaxProfiles+1; i++ {
+		m.profiles.get(fmt.Sprint(i), func() *Manager { return &Manager{Conf: &Config{}} })
+	}
+	ast.Len(m.profiles.list(), MaxProfiles)
+	_, ok := m.profiles.managers["0"]
+	ast.False(ok)
+
+	// an evicted profile still serves the requests given it, without keeping the readers
+	m = newTestManager(t, "country")
+	pm := m.profiles.get("evicted", func() *Manager { return m.newProfileManager("evicted", &Profile{}) })
+	info, err := pm.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("中国", info.Values()[0])
+	ast.NotNil(pm.ipv4.loaded())
+	for i := 0; i < MaxProfiles; i++ {
+		m.profiles.get(fmt.Sprint(i), func() *Manager { return &Manager{Conf: &Config{}} })
+	}
+	ast.NotContains(m.profiles.list(), pm)
+	ast.Nil(pm.ipv4.loaded())
+	_, err = pm.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Nil(pm.ipv4.loaded())
+}
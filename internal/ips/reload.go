@@ -0,0 +1,414 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// ReloadDelay is the time to wait after the last change of a database file before reloading,
+// so that a file being written is not loaded half way.
+const ReloadDelay = time.Second
+
+// readerHolder holds the current reader of an IP version, which can be swapped at runtime.
+type readerHolder struct {
+	mu      sync.Mutex
+	current atomic.Pointer[readerHandle]
+
+	// closed holders do not keep the readers they load, see close.
+	closed bool
+}
+
+// readerHandle is a loaded reader with the number of requests in flight.
+// A retired handle is closed when the last request releases it.
+type readerHandle struct {
+	format.Reader
+	name    string
+	version *DatabaseVersion
+	cache   *ipio.CachedReader
+
+	refs      atomic.Int64
+	retired   atomic.Bool
+	closeOnce sync.Once
+}
+
+// DatabaseVersion describes a loaded database reader.
+type DatabaseVersion struct {
+	Format    string          `json:"format"`
+	IPVersion int             `json:"ip_version"`
+	Fields    []string        `json:"fields"`
+	Files     []*DatabaseFile `json:"files"`
+	LoadTime  time.Time       `json:"load_time"`
+}
+
+// DatabaseFile describes a database file of a loaded reader.
+type DatabaseFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// acquire returns the current reader, loading it by load if it has not been loaded yet.
+// The returned handle must be released after use.
+func (h *readerHolder) acquire(load func() (*readerHandle, error)) (*readerHandle, error) {
+	for {
+		handle := h.current.Load()
+		if handle == nil {
+			h.mu.Lock()
+			if h.closed {
+				h.mu.Unlock()
+				return h.acquireClosed(load)
+			}
+			if h.current.Load() == nil {
+				handle, err := load()
+				if err != nil {
+					h.mu.Unlock()
+					return nil, err
+				}
+				h.current.Store(handle)
+			}
+			h.mu.Unlock()
+			continue
+		}
+
+		handle.refs.Add(1)
+		// the handle may have been swapped out before it was referenced
+		if h.current.Load() != handle {
+			handle.release()
+			continue
+		}
+		return handle, nil
+	}
+}
+
+// acquireClosed loads a reader for a single request of a closed holder.
+// The reader is not kept, and is closed when the request releases it.
+func (h *readerHolder) acquireClosed(load func() (*readerHandle, error)) (*readerHandle, error) {
+	handle, err := load()
+	if err != nil {
+		return nil, err
+	}
+	handle.refs.Add(1)
+	handle.retire()
+	return handle, nil
+}
+
+// swap replaces the current reader, and closes the previous one after the requests in flight are done.
+// A closed holder does not take the new reader, which is closed instead.
+func (h *readerHolder) swap(handle *readerHandle) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed && handle != nil {
+		handle.retire()
+		return
+	}
+	if prev := h.current.Swap(handle); prev != nil {
+		prev.retire()
+	}
+}
+
+// close closes the current reader after the requests in flight are done, and keeps the holder
+// from holding a reader again. It is used for the readers that are no longer reloaded,
+// so that a request still using them does not leave a reader open.
+func (h *readerHolder) close() {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	h.swap(nil)
+}
+
+// loaded returns the current reader without referencing it, or nil if it has not been loaded.
+func (h *readerHolder) loaded() *readerHandle {
+	return h.current.Load()
+}
+
+// release releases a handle returned by acquire.
+func (h *readerHandle) release() {
+	if h.refs.Add(-1) == 0 && h.retired.Load() {
+		h.close()
+	}
+}
+
+// retire marks the handle as swapped out, and closes it if no request is in flight.
+func (h *readerHandle) retire() {
+	h.retired.Store(true)
+	if h.refs.Load() == 0 {
+		h.close()
+	}
+}
+
+func (h *readerHandle) close() {
+	h.closeOnce.Do(func() {
+		if err := h.Reader.Close(); err != nil {
+			log.Debug("reader.Close error: ", err)
+		}
+	})
+}
+
+// loadReader creates the reader named name of the database files and records their versions.
+func (m *Manager) loadReader(name string, _format, file []string) (*readerHandle, error) {
+	reader, err := m.createReader(_format, file, false)
+	if err != nil {
+		log.Debug("createReader error: ", err)
+		return nil, err
+	}
+
+	meta := reader.Meta()
+	version := &DatabaseVersion{
+		Format:    meta.Format,
+		IPVersion: meta.IPVersion,
+		Fields:    meta.Fields,
+		Files:     make([]*DatabaseFile, 0, len(file)),
+		LoadTime:  time.Now(),
+	}
+	manifest, err := LoadManifest(m.Conf.IPSDir)
+	if err != nil {
+		log.Debug("LoadManifest error: ", err)
+	}
+	for _, f := range file {
+		path := m.databasePath(f)
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		dbFile := &DatabaseFile{
+			Path:    path,
+			Size:    stat.Size(),
+			ModTime: stat.ModTime(),
+		}
+		if manifest != nil && filepath.Dir(path) == filepath.Clean(m.Conf.IPSDir) {
+			if entry, ok := manifest.Files[filepath.Base(path)]; ok {
+				dbFile.SHA256 = entry.SHA256
+			}
+		}
+		version.Files = append(version.Files, dbFile)
+	}
+
+	if hybrid, ok := reader.(*ipio.HybridReader); ok {
+		hybrid.OnFind = func(index int, hit bool) {
+			observeHybridSource(name, file[index], hit)
+		}
+	}
+
+	// the cache of a reader is dropped with it, so that a reload invalidates the cached data
+	var cache *ipio.CachedReader
+	if m.Conf.CacheSize > 0 {
+		cache = ipio.NewCachedReader(reader, m.Conf.CacheSize)
+		cache.OnFind = func(hit bool) {
+			observeCache(name, hit)
+		}
+		reader = cache
+	}
+
+	handle := &readerHandle{
+		Reader:  reader,
+		name:    name,
+		version: version,
+		cache:   cache,
+	}
+	observeDatabase(handle)
+
+	return handle, nil
+}
+
+// loadIPv4Reader creates the IPv4 reader from the configuration.
+func (m *Manager) loadIPv4Reader() (*readerHandle, error) {
+	return m.loadReader(m.readerName("ipv4"), m.Conf.IPv4Format, m.Conf.IPv4File)
+}
+
+// loadIPv6Reader creates the IPv6 reader from the configuration.
+func (m *Manager) loadIPv6Reader() (*readerHandle, error) {
+	return m.loadReader(m.readerName("ipv6"), m.Conf.IPv6Format, m.Conf.IPv6File)
+}
+
+// Close closes the loaded readers, including the readers of the cached profiles,
+// after the requests in flight are done. The readers are loaded again on the next lookup.
+func (m *Manager) Close() {
+	m.ipv4.swap(nil)
+	m.ipv6.swap(nil)
+	for _, pm := range m.profiles.list() {
+		pm.Close()
+	}
+}
+
+// evict closes the readers of a Manager removed from the profile cache for good.
+// A request that still uses the Manager loads readers that are closed after the lookup.
+func (m *Manager) evict() {
+	m.ipv4.close()
+	m.ipv6.close()
+}
+
+// shutdown closes the readers for good after the services have stopped, including the readers of
+// the cached profiles. A request still in flight loads readers that are closed after the lookup.
+func (m *Manager) shutdown() {
+	m.evict()
+	for _, pm := range m.profiles.list() {
+		pm.evict()
+	}
+}
+
+// Reload creates new readers of the loaded IPv4 and IPv6 databases and swaps them in,
+// including the readers of the cached profiles.
+// If a database fails to load, the current reader is kept and the error is returned.
+func (m *Manager) Reload() error {
+	ret := m.reloadReaders()
+	for _, pm := range m.profiles.list() {
+		if err := pm.Reload(); err != nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// reloadReaders reloads the loaded IPv4 and IPv6 readers, without the readers of the cached profiles.
+func (m *Manager) reloadReaders() error {
+	var ret error
+	if err := m.reload(&m.ipv4, m.loadIPv4Reader); err != nil {
+		log.Errorf("reload %s database failed: %s", m.readerName("ipv4"), err)
+		ret = err
+	}
+	if err := m.reload(&m.ipv6, m.loadIPv6Reader); err != nil {
+		log.Errorf("reload %s database failed: %s", m.readerName("ipv6"), err)
+		ret = err
+	}
+	return ret
+}
+
+// reload swaps in a new reader of the holder. A reader that has not been loaded is left to be loaded lazily.
+func (m *Manager) reload(holder *readerHolder, load func() (*readerHandle, error)) error {
+	if holder.loaded() == nil {
+		return nil
+	}
+	handle, err := load()
+	if err != nil {
+		return err
+	}
+	holder.swap(handle)
+	for _, file := range handle.version.Files {
+		log.Infof("database reloaded: %s", file.Path)
+	}
+	return nil
+}
+
+// readerVersion returns the version of the reader of the holder, loading it if it has not been loaded.
+func readerVersion(holder *readerHolder, load func() (*readerHandle, error)) (*DatabaseVersion, error) {
+	handle, err := holder.acquire(load)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.version, nil
+}
+
+// DatabaseVersions returns the versions of the loaded IPv4 and IPv6 readers.
+// Readers that have not been loaded are omitted.
+func (m *Manager) DatabaseVersions() map[string]*DatabaseVersion {
+	ret := make(map[string]*DatabaseVersion)
+	if handle := m.ipv4.loaded(); handle != nil {
+		ret["ipv4"] = handle.version
+	}
+	if handle := m.ipv6.loaded(); handle != nil {
+		ret["ipv6"] = handle.version
+	}
+	return ret
+}
+
+// WatchDatabases reloads the databases when their files change or a SIGHUP signal is received,
+// until ctx is done.
+func (m *Manager) WatchDatabases(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Debug("fsnotify.NewWatcher error: ", err)
+		return err
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	// Watch the directories instead of the files, since an updated file replaces the previous one.
+	files := make(map[string]bool)
+	watchFiles := append(append([]string{}, m.Conf.IPv4File...), m.Conf.IPv6File...)
+	for _, profile := range m.Conf.Profiles {
+		if profile != nil {
+			watchFiles = append(append(watchFiles, profile.IPv4File...), profile.IPv6File...)
+		}
+	}
+	for _, file := range watchFiles {
+		path, err := filepath.Abs(m.databasePath(file))
+		if err != nil {
+			continue
+		}
+		if !files[path] {
+			files[path] = true
+			if err := watcher.Add(filepath.Dir(path)); err != nil {
+				log.Debug("watcher.Add error: ", err)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return errors.ErrFileNotFound
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	timer := time.NewTimer(ReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			log.Info("SIGHUP received, reloading databases")
+			_ = m.Reload()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !files[filepath.Clean(event.Name)] || event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			log.Debug("database file changed: ", event)
+			timer.Reset(ReloadDelay)
+		case <-timer.C:
+			_ = m.Reload()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Debug("watcher error: ", err)
+		}
+	}
+}
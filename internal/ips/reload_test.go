@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/model"
+)
+
+type closeCounter struct {
+	closed atomic.Int32
+}
+
+func (c *closeCounter) Meta() *model.Meta                     { return &model.Meta{} }
+func (c *closeCounter) Find(ip net.IP) (*model.IPInfo, error) { return &model.IPInfo{IP: ip}, nil }
+func (c *closeCounter) SetOption(option interface{}) error    { return nil }
+func (c *closeCounter) Close() error {
+	c.closed.Add(1)
+	return nil
+}
+
+func TestReaderHolder(t *testing.T) {
+	ast := assert.New(t)
+
+	holder := &readerHolder{}
+	first, second := &closeCounter{}, &closeCounter{}
+	loads := 0
+	load := func() (*readerHandle, error) {
+		loads++
+		return &readerHandle{Reader: first, version: &DatabaseVersion{}}, nil
+	}
+
+	// lazy load once
+	h1, err := holder.acquire(load)
+	ast.Nil(err)
+	h2, err := holder.acquire(load)
+	ast.Nil(err)
+	ast.Equal(1, loads)
+	ast.Equal(h1, h2)
+	h2.release()
+
+	// the previous reader is closed after the request in flight is done
+	holder.swap(&readerHandle{Reader: second, version: &DatabaseVersion{}})
+	ast.Equal(int32(0), first.closed.Load())
+	h3, err := holder.acquire(load)
+	ast.Nil(err)
+	ast.Equal(second, h3.Reader)
+	h1.release()
+	ast.Equal(int32(1), first.closed.Load())
+
+	// a reader without requests in flight is closed when swapped out
+	h3.release()
+	holder.swap(&readerHandle{Reader: &closeCounter{}, version: &DatabaseVersion{}})
+	ast.Equal(int32(1), second.closed.Load())
+	ast.Equal(1, loads)
+}
+
+func TestReaderHolderClose(t *testing.T) {
+	ast := assert.New(t)
+
+	holder := &readerHolder{}
+	readers := make([]*closeCounter, 0)
+	load := func() (*readerHandle, error) {
+		reader := &closeCounter{}
+		readers = append(readers, reader)
+		return &readerHandle{Reader: reader, version: &DatabaseVersion{}}, nil
+	}
+
+	h1, err := holder.acquire(load)
+	ast.Nil(err)
+	holder.close()
+	ast.Nil(holder.loaded())
+	ast.Equal(int32(0), readers[0].closed.Load())
+	h1.release()
+	ast.Equal(int32(1), readers[0].closed.Load())
+
+	// a closed holder loads a reader per request, closed when released
+	h2, err := holder.acquire(load)
+	ast.Nil(err)
+	ast.Len(readers, 2)
+	ast.Nil(holder.loaded())
+	ast.Equal(int32(0), readers[1].closed.Load())
+	h2.release()
+	ast.Equal(int32(1), readers[1].closed.Load())
+
+	// and does not take a reloaded reader
+	reloaded := &closeCounter{}
+	holder.swap(&readerHandle{Reader: reloaded, version: &DatabaseVersion{}})
+	ast.Nil(holder.loaded())
+	ast.Equal(int32(1), reloaded.closed.Load())
+}
+
+func TestManagerShutdown(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	pm := m.profiles.get("cached", func() *Manager { return m.newProfileManager("cached", &Profile{}) })
+	_, err := m.parseIP("200.1.1.1")
+	ast.Nil(err)
+	_, err = pm.parseIP("200.1.1.1")
+	ast.Nil(err)
+
+	// a request after the shutdown does not leave a reader open
+	m.shutdown()
+	ast.Nil(m.ipv4.loaded())
+	ast.Nil(pm.ipv4.loaded())
+	info, err := m.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("中国", info.Values()[0])
+	ast.Nil(m.ipv4.loaded())
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/ipnet"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// SearchWriterFormat is the format of the writer collecting the networks of the search.
+const SearchWriterFormat = "search"
+
+// SearchFile searches the networks matching each condition, and writes their minimal CIDRs to the output file.
+// The CIDRs of each condition follow a "# condition" line if there are multiple conditions.
+func (m *Manager) SearchFile(where []string, outputFile string) error {
+	result, err := m.Search(where)
+	if err != nil {
+		return err
+	}
+
+	output := io.Writer(os.Stdout)
+	var atomicFile *util.AtomicFile
+	if len(outputFile) != 0 {
+		atomicFile, err = util.CreateAtomicFile(outputFile)
+		if err != nil {
+			log.Debug("util.CreateAtomicFile error: ", err)
+			return err
+		}
+		defer func() {
+			_ = atomicFile.Abort()
+		}()
+		output = atomicFile.File
+	}
+
+	for i, ipNets := range result {
+		if len(where) > 1 {
+			if _, err := fmt.Fprintf(output, "# %s\n", where[i]); err != nil {
+				log.Debug("fmt.Fprintf error: ", err)
+				return err
+			}
+		}
+		for _, ipNet := range ipNets {
+			if _, err := fmt.Fprintln(output, ipNet.String()); err != nil {
+				log.Debug("fmt.Fprintln error: ", err)
+				return err
+			}
+		}
+	}
+
+	if atomicFile != nil {
+		if err := atomicFile.Commit(nil); err != nil {
+			log.Debug("atomicFile.Commit error: ", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Search walks the IPv4 and IPv6 databases, and returns the minimal CIDRs of the networks matching each condition.
+// The conditions are in the syntax of the field selector rules, e.g. "country=中国&province=广东/广西&isp=!移动".
+// The databases are walked as by dump, so the fields are matched after the data rewriting and the translation,
+// and the adjacent networks are told apart by the dump fields. A database serving both IPv4 and IPv6 is walked once.
+func (m *Manager) Search(where []string) ([][]*net.IPNet, error) {
+	rules := make([]*operate.FieldSelectorRule, 0, len(where))
+	for _, condition := range where {
+		values, err := url.ParseQuery(condition)
+		if err != nil || len(values) == 0 {
+			log.Debug("url.ParseQuery error: ", condition, err)
+			return nil, errors.ErrInvalidCondition
+		}
+		rules = append(rules, &operate.FieldSelectorRule{Condition: values})
+	}
+
+	collector := &searchCollector{
+		rules:  rules,
+		ranges: make([]ipnet.Ranges, len(rules)),
+	}
+	if strings.Join(m.Conf.IPv4File, ",") == strings.Join(m.Conf.IPv6File, ",") &&
+		strings.Join(m.Conf.IPv4Format, ",") == strings.Join(m.Conf.IPv6Format, ",") {
+		if err := m.searchDatabase(m.Conf.IPv4Format, m.Conf.IPv4File, collector, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		isIPv4 := true
+		if err := m.searchDatabase(m.Conf.IPv4Format, m.Conf.IPv4File, collector, &isIPv4); err != nil {
+			return nil, err
+		}
+		isIPv6 := false
+		if err := m.searchDatabase(m.Conf.IPv6Format, m.Conf.IPv6File, collector, &isIPv6); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([][]*net.IPNet, len(rules))
+	for i, ranges := range collector.ranges {
+		result[i] = ipnet.Ranges(ranges).IPNets()
+	}
+	return result, nil
+}
+
+// searchDatabase walks the database with the collector.
+// The networks are restricted to IPv4 or not IPv4 by the filter, if any.
+func (m *Manager) searchDatabase(_format, file []string, collector *searchCollector, filter *bool) error {
+	if len(file) == 0 {
+		return nil
+	}
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return errors.ErrInvalidFormat
+	}
+
+	reader, err := m.createReader(_format, file, true)
+	if err != nil {
+		log.Debug("m.createReader error: ", err)
+		return err
+	}
+	defer reader.Close()
+
+	collector.filter = filter
+	if err := ipio.NewStandardDumper(reader, collector).Dump(m.Conf.ReaderJobs); err != nil {
+		log.Debug("dumper.Dump error: ", err)
+		return err
+	}
+	return nil
+}
+
+// searchCollector is a writer of the dumper, collecting the ranges of the IP information matching the rules.
+type searchCollector struct {
+	rules  []*operate.FieldSelectorRule
+	ranges []ipnet.Ranges
+	filter *bool
+}
+
+// SetOption is not supported by the collector.
+func (c *searchCollector) SetOption(_ interface{}) error {
+	return nil
+}
+
+// Insert adds the range of the IP information to the rules it matches.
+func (c *searchCollector) Insert(info *model.IPInfo) error {
+	if info.IPNet == nil {
+		return nil
+	}
+	if c.filter != nil && (info.IPNet.Start.To4() != nil) != *c.filter {
+		return nil
+	}
+	for i, rule := range c.rules {
+		if rule.IsMatch(info) {
+			c.ranges[i] = append(c.ranges[i], ipnet.Range{
+				Start: info.IPNet.Start.To16(),
+				End:   info.IPNet.End.To16(),
+			})
+		}
+	}
+	return nil
+}
+
+// WriteTo is not supported by the collector, the ranges are merged by Search.
+func (c *searchCollector) WriteTo(_ io.Writer) (int64, error) {
+	return 0, nil
+}
+
+// WriterFormat returns the format of the collector.
+func (c *searchCollector) WriterFormat() string {
+	return SearchWriterFormat
+}
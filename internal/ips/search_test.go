@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPlainIPv6DB is an IPv6 database in the plain format.
+const testPlainIPv6DB = `# Meta: {"MetaVersion": 1, "Format": "plain", "IPVersion": 2, "Fields": ["country", "province", "city", "isp"], "FieldAlias": {}}
+::/1	保留,,,
+8000::/2	中国,广东,深圳,移动
+c000::/3	中国,广东,广州,移动
+e000::/3	美国,,,
+`
+
+func TestSearch(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	ret, err := m.Search([]string{"country=中国", "city=!深圳&country=中国/美国", "isp=移动"})
+	ast.Nil(err)
+	ast.Equal([]string{"192.0.0.0/2"}, ipNetStrings(ret[0]))
+	ast.Equal([]string{"128.0.0.0/2", "224.0.0.0/3"}, ipNetStrings(ret[1]))
+	ast.Empty(ret[2])
+
+	_, err = m.Search([]string{"%zz"})
+	ast.NotNil(err)
+	_, err = m.Search([]string{""})
+	ast.NotNil(err)
+}
+
+func TestSearchIPv6(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	file := filepath.Join(t.TempDir(), "ipv6.txt")
+	ast.Nil(os.WriteFile(file, []byte(testPlainIPv6DB), 0644))
+	m.Conf.IPv6File = []string{file}
+	m.Conf.IPv6Format = []string{"plain"}
+
+	ret, err := m.Search([]string{"province=广东"})
+	ast.Nil(err)
+	ast.Equal([]string{"192.0.0.0/2", "8000::/2", "c000::/3"}, ipNetStrings(ret[0]))
+}
+
+func ipNetStrings(ipNets []*net.IPNet) []string {
+	ret := make([]string, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		ret = append(ret, ipNet.String())
+	}
+	return ret
+}
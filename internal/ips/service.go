@@ -17,25 +17,45 @@
 package ips
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
+	"encoding/json"
+	"io"
 	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pires/go-proxyproto"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sjzar/ips/internal/parser"
+	"github.com/sjzar/ips/pkg/errors"
 	"github.com/sjzar/ips/pkg/model"
 )
 
 // Service initializes and runs the main web service for the application.
-// It sets up middlewares, routes and starts the HTTP server.
-func (m *Manager) Service() {
+// It sets up middlewares, routes and starts the HTTP server, and the gRPC server if configured.
+// It returns an error if the configuration is invalid or a server fails, which stops the other server.
+func (m *Manager) Service() error {
 	router := gin.New()
 
+	resolver, err := NewClientIPResolver(m.Conf.TrustedProxies)
+	if err != nil {
+		log.Debug("NewClientIPResolver error: ", err)
+		return err
+	}
+	m.clientIP = resolver
+
 	// Handle error from SetTrustedProxies
-	if err := router.SetTrustedProxies(nil); err != nil {
+	if err := router.SetTrustedProxies(m.Conf.TrustedProxies); err != nil {
 		log.Error("Failed to set trusted proxies:", err)
 	}
 
@@ -44,15 +64,142 @@ func (m *Manager) Service() {
 		gin.Recovery(),
 		gin.Logger(),
 	)
+	if m.Conf.Metrics {
+		router.Use(MetricsMiddleware)
+	}
+
+	// Access control
+	corsMiddleware, err := m.CORSMiddleware()
+	if err != nil {
+		log.Debug("m.CORSMiddleware error: ", err)
+		return err
+	}
+	allowlist, err := m.AllowlistMiddleware()
+	if err != nil {
+		log.Debug("m.AllowlistMiddleware error: ", err)
+		return err
+	}
+	useMiddlewares(&router.RouterGroup, corsMiddleware, allowlist)
 
 	m.router = router
 
 	m.InitRouter()
 
-	// Handle error from Run
-	if err := m.router.Run(m.Conf.Addr); err != nil {
-		log.Error("Failed to run server:", err)
+	// Shut down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A failed service stops the others
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	grpcErr := make(chan error, 1)
+	if len(m.Conf.GRPCAddr) != 0 {
+		go func() {
+			err := m.GRPCService(ctx)
+			if err != nil {
+				cancel()
+			}
+			grpcErr <- err
+		}()
+	} else {
+		grpcErr <- nil
+	}
+
+	// Load the databases before the first lookup, and reload them when they are updated
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.Preload(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		if err := m.WatchDatabases(ctx); err != nil {
+			log.Error("Failed to watch databases:", err)
+		}
+	}()
+
+	err = m.serveHTTP(ctx)
+	if err != nil {
+		log.Debug("m.serveHTTP error: ", err)
+	}
+
+	// Wait for the gRPC service to stop
+	cancel()
+	if grpcErr := <-grpcErr; grpcErr != nil {
+		log.Debug("m.GRPCService error: ", grpcErr)
+		if err == nil {
+			err = grpcErr
+		}
+	}
+
+	// Close the readers after nothing is serving or reloading them
+	wg.Wait()
+	m.shutdown()
+
+	return err
+}
+
+// serveHTTP serves the router on the listen addresses until ctx is done,
+// then shuts down gracefully, waiting for the requests in flight up to the shutdown timeout.
+func (m *Manager) serveHTTP(ctx context.Context) error {
+	server := &http.Server{Handler: m.router}
+	if len(m.Conf.TLSCert) != 0 || len(m.Conf.TLSKey) != 0 {
+		reloader, err := newCertReloader(m.Conf.TLSCert, m.Conf.TLSKey)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	listeners := make([]net.Listener, 0)
+	for _, addr := range m.Conf.ListenAddrs() {
+		listener, err := Listen(addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return err
+		}
+		if m.Conf.ProxyProtocol {
+			if len(m.Conf.TrustedProxies) == 0 && listener.Addr().Network() != "unix" {
+				log.Warnf("no trusted proxies, PROXY protocol headers on %s are ignored", listener.Addr())
+			}
+			listener = &proxyproto.Listener{
+				Listener: listener,
+				Policy:   m.clientIP.ProxyProtocolPolicy,
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+
+	errChan := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		log.Infof("HTTP service listening on %s", listener.Addr())
+		go func(listener net.Listener) {
+			if server.TLSConfig != nil {
+				errChan <- server.ServeTLS(listener, "", "")
+				return
+			}
+			errChan <- server.Serve(listener)
+		}(listener)
 	}
+
+	select {
+	case err := <-errChan:
+		_ = server.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down HTTP service")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(m.Conf.ShutdownTimeoutS)*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
 }
 
 // EFS holds embedded file system data for static assets.
@@ -68,16 +215,64 @@ func (m *Manager) InitRouter() {
 	m.router.StaticFileFS("/favicon.ico", "./favicon.ico", http.FS(staticDir))
 	m.router.StaticFileFS("/", "./index.htm", http.FS(staticDir))
 
+	// Probes and API specification
+	m.router.GET("/healthz", m.Healthz)
+	m.router.GET("/readyz", m.Readyz)
+	m.router.GET("/openapi.json", m.GetOpenAPI)
+
+	// The lookup APIs are rate limited, not the probes and the metrics.
+	// The rate limiter is shared by the APIs.
+	rateLimit, apiKey := m.RateLimitMiddleware(), m.APIKeyMiddleware()
+
 	// API Router
 	api := m.router.Group("/api")
+	useMiddlewares(api, rateLimit, apiKey)
 	{
 		api.GET("/v1/ip", m.GetIP)
+		api.GET("/v1/myip", m.GetMyIP)
 		api.GET("/v1/query", m.GetQuery)
+		api.GET("/v1/versions", m.GetVersions)
+		api.GET("/v1/meta", m.GetMeta)
+		api.POST("/v1/batch", m.PostBatch)
+	}
+
+	// GeoIP2 web service compatible API, authenticated by the GeoIP2 accounts, or else by the API keys
+	geoip := m.router.Group("/geoip/v2.1")
+	if len(m.Conf.GeoIP2Accounts) != 0 {
+		useMiddlewares(geoip, rateLimit, m.GeoIP2Auth)
+	} else {
+		useMiddlewares(geoip, rateLimit, apiKey)
+	}
+	{
+		geoip.GET("/:endpoint/:ip", m.GetGeoIP2)
+	}
+
+	// ipinfo.io compatible API
+	ipinfo := m.router.Group("/ipinfo")
+	useMiddlewares(ipinfo, rateLimit, apiKey)
+	{
+		ipinfo.GET("", m.GetIPInfo)
+		ipinfo.GET("/:ip", m.GetIPInfo)
+		ipinfo.GET("/:ip/:field", m.GetIPInfoField)
+		ipinfo.POST("/batch", m.PostIPInfoBatch)
+	}
+
+	if m.Conf.Metrics {
+		m.router.GET("/metrics", gin.WrapH(MetricsHandler()))
 	}
 
 	m.router.NoRoute(m.NoRoute)
 }
 
+// useMiddlewares adds the middlewares to the group, skipping the disabled ones.
+func useMiddlewares(group *gin.RouterGroup, middlewares ...gin.HandlerFunc) {
+	for _, middleware := range middlewares {
+		if middleware != nil {
+			group.Use(middleware)
+		}
+	}
+}
+
 // NoRoute handles 404 Not Found errors. If the request URL starts with "/api"
 // or "/static", it responds with a JSON error. Otherwise, it redirects to the root path.
 func (m *Manager) NoRoute(c *gin.Context) {
@@ -93,18 +288,59 @@ func (m *Manager) NoRoute(c *gin.Context) {
 
 // GetIP handles the GET /v1/ip endpoint. It takes an IP as a query parameter
 // and returns its associated information in JSON format.
+// Without the parameter, it returns the information of the client IP like GetMyIP.
 // Example:
 // GET /v1/ip?ip=<ip>
 // Response:
 // {}
 func (m *Manager) GetIP(c *gin.Context) {
-	info, err := m.parseIP(c.Query("ip"))
+	ip := c.Query("ip")
+	if len(ip) == 0 {
+		m.GetMyIP(c)
+		return
+	}
+
+	lookup, useDBFields, err := m.requestProfile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := lookup.parseIP(ip)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, info.Output(m.Conf.UseDBFields))
+	c.JSON(http.StatusOK, info.Output(useDBFields))
+}
+
+// GetMyIP handles the GET /v1/myip endpoint. It returns the information of the client IP,
+// resolved from the trusted proxy headers and the PROXY protocol.
+// Example:
+// GET /v1/myip
+// Response:
+// {}
+func (m *Manager) GetMyIP(c *gin.Context) {
+	ip, err := m.clientIP.ClientIP(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lookup, useDBFields, err := m.requestProfile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := lookup.parseIP(ip.String())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, info.Output(useDBFields))
 }
 
 // GetQuery handles the GET /v1/query endpoint. It takes a text string as a query
@@ -120,12 +356,18 @@ func (m *Manager) GetQuery(c *gin.Context) {
 		text = c.Request.URL.RawQuery
 	}
 
+	lookup, useDBFields, err := m.requestProfile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	ret := &model.DataList{}
 
 	tp := parser.NewTextParser(text).Parse()
 
 	for _, segment := range tp.Segments {
-		info, err := m.parseSegment(segment)
+		info, err := lookup.parseSegment(segment)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -133,7 +375,7 @@ func (m *Manager) GetQuery(c *gin.Context) {
 
 		switch v := info.(type) {
 		case *model.IPInfo:
-			ret.AddItem(v.Output(m.Conf.UseDBFields))
+			ret.AddItem(v.Output(useDBFields))
 		case *model.DomainInfo:
 			ret.AddDomain(v)
 		}
@@ -141,3 +383,90 @@ func (m *Manager) GetQuery(c *gin.Context) {
 
 	c.JSON(http.StatusOK, ret)
 }
+
+// PostBatch handles the POST /v1/batch endpoint. It takes a JSON array or an NDJSON stream of IPs
+// and returns the information of each IP in order, in the same type as the request.
+// The response is streamed, an IP that fails to look up is returned with an error,
+// and a malformed or oversized batch ends with an error item.
+// Example:
+// POST /v1/batch
+// ["1.1.1.1", "8.8.8.8"]
+// Response:
+// [{},{"ip": "<ip>", "error": "<error>"}]
+func (m *Manager) PostBatch(c *gin.Context) {
+	lookup, useDBFields, err := m.requestProfile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reader, err := NewBatchReader(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Read the rest of the request while the response is streamed.
+	if err := http.NewResponseController(c.Writer).EnableFullDuplex(); err != nil {
+		log.Debug("EnableFullDuplex error: ", err)
+	}
+
+	c.Status(http.StatusOK)
+	if reader.Array {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		_, _ = c.Writer.WriteString("[")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+	for i := 0; ; i++ {
+		ip, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var item interface{}
+		switch {
+		case err != nil:
+			item = gin.H{"error": err.Error()}
+		case m.Conf.BatchMaxSize > 0 && i >= m.Conf.BatchMaxSize:
+			err = errors.ErrBatchTooLarge
+			item = gin.H{"error": err.Error()}
+		default:
+			if info, err := lookup.parseIP(ip); err != nil {
+				item = gin.H{"ip": ip, "error": err.Error()}
+			} else {
+				item = info.Output(useDBFields)
+			}
+		}
+
+		if reader.Array && i > 0 {
+			_, _ = c.Writer.WriteString(",")
+		}
+		if err := encoder.Encode(item); err != nil {
+			log.Debug("encoder.Encode error: ", err)
+			return
+		}
+		if (i+1)%BatchFlushSize == 0 {
+			c.Writer.Flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if reader.Array {
+		_, _ = c.Writer.WriteString("]")
+	}
+}
+
+// GetVersions handles the GET /v1/versions endpoint. It returns the versions of the
+// loaded IPv4 and IPv6 databases, which change when the databases are reloaded.
+// Example:
+// GET /v1/versions
+// Response:
+// {"ipv4": {"format": "", "files": [{}]}, "ipv6": {}}
+func (m *Manager) GetVersions(c *gin.Context) {
+	c.JSON(http.StatusOK, m.DatabaseVersions())
+}
@@ -0,0 +1,334 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"github.com/sjzar/ips/format/geo"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// ShellPrompt is the prompt of the interactive shell.
+const ShellPrompt = "ips> "
+
+// Settings of the shell changed by the :set command, named after the flags of the query.
+const (
+	ShellSetFields     = "fields"
+	ShellSetLang       = "lang"
+	ShellSetOutput     = "output"
+	ShellSetHybridMode = "hybrid-mode"
+)
+
+// ShellOutputTypes lists the output types supported by the shell.
+var ShellOutputTypes = []string{
+	OutputTypeText,
+	OutputTypeJSON,
+	OutputTypeCSV,
+	OutputTypeTSV,
+	OutputTypeNDJSON,
+	OutputTypeYAML,
+	OutputTypeTable,
+}
+
+// shellHelp is the output of the :help command.
+const shellHelp = `Type IPs, domains or any text to query, or paste a block of text.
+Commands:
+  :set                  show the settings
+  :set <key> <value>    change a setting, one of fields, lang, output and hybrid-mode
+  :info                 show the meta of the loaded databases
+  :reload               reload the databases
+  :help                 show this help
+  :quit                 exit the shell, also :exit or Ctrl-D
+`
+
+// Shell is an interactive session of queries. The readers of the Manager stay loaded across the queries,
+// and are reloaded only when a setting they depend on is changed.
+type Shell struct {
+	m   *Manager
+	out io.Writer
+}
+
+// NewShell creates a Shell of the Manager writing to out.
+func (m *Manager) NewShell(out io.Writer) *Shell {
+	return &Shell{
+		m:   m,
+		out: out,
+	}
+}
+
+// Run reads and executes the lines of in until :quit or the end of the input.
+// A terminal is read with a line editor, which keeps the history of the session
+// and passes the pasted text to the query even if a line looks like a command.
+func (s *Shell) Run(in io.Reader) error {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return s.runTerminal(f)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !s.execute(scanner.Text(), false) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Debug("scanner.Err error: ", err)
+		return err
+	}
+	return nil
+}
+
+// runTerminal runs the shell on the terminal f in raw mode.
+func (s *Shell) runTerminal(f *os.File) error {
+	fd := int(f.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		log.Debug("term.MakeRaw error: ", err)
+		return err
+	}
+	defer func() {
+		_ = term.Restore(fd, state)
+	}()
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, s.out}, ShellPrompt)
+	if width, height, err := term.GetSize(fd); err == nil && width > 0 && height > 0 {
+		_ = t.SetSize(width, height)
+	}
+	t.SetBracketedPasteMode(true)
+	defer t.SetBracketedPasteMode(false)
+
+	// the line feeds are written as is in raw mode, the terminal translates them
+	out, logOut := s.out, log.StandardLogger().Out
+	s.out = t
+	log.SetOutput(t)
+	defer func() {
+		s.out = out
+		log.SetOutput(logOut)
+	}()
+
+	_, _ = fmt.Fprintln(t, "Type :help for help, :quit to exit.")
+	for {
+		line, err := t.ReadLine()
+		pasted := err == term.ErrPasteIndicator
+		if err != nil && !pasted {
+			if err == io.EOF {
+				return nil
+			}
+			log.Debug("t.ReadLine error: ", err)
+			return err
+		}
+		if !s.execute(line, pasted) {
+			return nil
+		}
+	}
+}
+
+// execute executes a line and writes the result or the error.
+// It returns false if the shell should quit.
+func (s *Shell) execute(line string, pasted bool) bool {
+	var ret string
+	var err error
+	quit := false
+	if pasted {
+		ret, err = s.Query(line)
+	} else {
+		ret, quit, err = s.Exec(line)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(s.out, "error: %s\n", err)
+		return !quit
+	}
+	if len(ret) != 0 {
+		if !strings.HasSuffix(ret, "\n") {
+			ret += "\n"
+		}
+		_, _ = io.WriteString(s.out, ret)
+	}
+	return !quit
+}
+
+// Exec executes a line typed in the shell, a command starting with ":" or a text to query.
+// It returns the output, and whether the shell should quit.
+func (s *Shell) Exec(line string) (string, bool, error) {
+	line = strings.TrimSpace(line)
+	if !isShellCommand(line) {
+		ret, err := s.Query(line)
+		return ret, false, err
+	}
+
+	args := strings.Fields(line[1:])
+	switch args[0] {
+	case "quit", "exit", "q":
+		return "", true, nil
+	case "help", "h":
+		return shellHelp, false, nil
+	case "info":
+		ret, err := s.info()
+		return ret, false, err
+	case "set":
+		ret, err := s.set(args[1:])
+		return ret, false, err
+	case "reload":
+		return "", false, s.m.Reload()
+	}
+	return "", false, errors.ErrUnknownShellCommand
+}
+
+// Query parses the text, which may be a pasted block of lines, and returns the results,
+// including the output held until the end of the results such as the table.
+func (s *Shell) Query(text string) (string, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return "", nil
+	}
+	ret, err := s.m.ParseText(text)
+	if err != nil {
+		s.m.FlushOutput()
+		return "", err
+	}
+	return ret + s.m.FlushOutput(), nil
+}
+
+// isShellCommand reports whether the line is a command. IPv6 addresses such as "::1" are not.
+func isShellCommand(line string) bool {
+	return len(line) > 1 && line[0] == ':' && (line[1] >= 'a' && line[1] <= 'z' || line[1] >= 'A' && line[1] <= 'Z')
+}
+
+// info returns the meta of the IPv4 and IPv6 readers, loading them if they have not been loaded.
+func (s *Shell) info() (string, error) {
+	buf := &strings.Builder{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	for _, slot := range s.m.readerSlots() {
+		if !slot.configured {
+			continue
+		}
+		handle, err := slot.holder.acquire(slot.load)
+		if err != nil {
+			return "", err
+		}
+		meta := handle.Meta()
+		version := handle.version
+		handle.release()
+
+		ipVersions := make([]string, 0, 2)
+		if meta.IsIPv4Support() {
+			ipVersions = append(ipVersions, "ipv4")
+		}
+		if meta.IsIPv6Support() {
+			ipVersions = append(ipVersions, "ipv6")
+		}
+		alias := make([]string, 0, len(meta.FieldAlias))
+		for k, v := range meta.FieldAlias {
+			alias = append(alias, k+"="+v)
+		}
+		sort.Strings(alias)
+
+		_, _ = fmt.Fprintf(w, "[%s]\n", slot.name)
+		_, _ = fmt.Fprintf(w, "format:\t%s\n", meta.Format)
+		_, _ = fmt.Fprintf(w, "ip_version:\t%s\n", strings.Join(ipVersions, ","))
+		_, _ = fmt.Fprintf(w, "fields:\t%s\n", strings.Join(meta.Fields, ","))
+		_, _ = fmt.Fprintf(w, "field_alias:\t%s\n", strings.Join(alias, ","))
+		for _, file := range version.Files {
+			_, _ = fmt.Fprintf(w, "file:\t%s (%d bytes, modified %s)\n", file.Path, file.Size, file.ModTime.Format("2006-01-02 15:04:05"))
+		}
+		_, _ = fmt.Fprintf(w, "load_time:\t%s\n", version.LoadTime.Format("2006-01-02 15:04:05"))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// set changes a setting, or returns the settings if no argument is given.
+// The readers are reloaded for the fields, the language and the hybrid mode,
+// and the setting is restored if they fail to reload.
+func (s *Shell) set(args []string) (string, error) {
+	conf := s.m.Conf
+	if len(args) == 0 {
+		buf := &strings.Builder{}
+		w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", ShellSetFields, conf.Fields)
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", ShellSetLang, conf.Lang)
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", ShellSetOutput, conf.OutputType)
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", ShellSetHybridMode, conf.HybridMode)
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	if len(args) != 2 {
+		return "", errors.ErrInvalidShellSetting
+	}
+
+	key, value := args[0], args[1]
+	switch key {
+	case ShellSetFields:
+		return "", s.reload(func(conf *Config) {
+			conf.Fields = value
+		})
+	case ShellSetLang:
+		if err := geo.CheckLanguage(value); err != nil {
+			return "", err
+		}
+		return "", s.reload(func(conf *Config) {
+			conf.Lang = value
+		})
+	case ShellSetOutput:
+		for _, outputType := range ShellOutputTypes {
+			if value == outputType {
+				conf.OutputType = value
+				return "", nil
+			}
+		}
+		return "", errors.ErrUnsupportedOutput
+	case ShellSetHybridMode:
+		if value != ipio.HybridAggregationMode && value != ipio.HybridComparisonMode {
+			return "", errors.ErrInvalidShellSetting
+		}
+		return "", s.reload(func(conf *Config) {
+			conf.HybridMode = value
+		})
+	}
+	return "", errors.ErrInvalidShellSetting
+}
+
+// reload applies the change of the configuration and reloads the loaded IPv4 and IPv6 readers.
+// The configuration is restored if the readers fail to reload.
+// The cached profiles keep the configuration they were created with, so their readers are not reloaded.
+func (s *Shell) reload(apply func(conf *Config)) error {
+	prev := *s.m.Conf
+	apply(s.m.Conf)
+	if err := s.m.reloadReaders(); err != nil {
+		log.Debug("m.reloadReaders error: ", err)
+		*s.m.Conf = prev
+		_ = s.m.reloadReaders()
+		return err
+	}
+	return nil
+}
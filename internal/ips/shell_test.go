@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestShellExec(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	m.Conf.TextFormat = "%origin [%values] "
+	m.Conf.TextValuesSep = " "
+	s := m.NewShell(&bytes.Buffer{})
+
+	ret, quit, err := s.Exec("200.1.1.1")
+	ast.Nil(err)
+	ast.False(quit)
+	ast.Equal("200.1.1.1 [中国 深圳] ", ret)
+
+	// the readers are reloaded with the new fields
+	_, _, err = s.Exec(":set fields country,isp")
+	ast.Nil(err)
+	ret, _, err = s.Exec("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal("200.1.1.1 [中国 电信] ", ret)
+
+	_, _, err = s.Exec(":set output table")
+	ast.Nil(err)
+	ret, _, err = s.Exec("200.1.1.1 230.1.1.1")
+	ast.Nil(err)
+	ast.Contains(ret, "| 200.1.1.1 | 192.0.0.0/3 | 中国    | 电信 |")
+	ast.Contains(ret, "| 230.1.1.1 | 224.0.0.0/3 | 中国    |      |")
+
+	ret, _, err = s.Exec(":set")
+	ast.Nil(err)
+	ast.Contains(ret, "fields       country,isp\n")
+	ast.Contains(ret, "output       table\n")
+
+	ret, _, err = s.Exec(":info")
+	ast.Nil(err)
+	ast.Contains(ret, "[ipv4]\n")
+	ast.Contains(ret, "format:       plain\n")
+	ast.Contains(ret, "fields:       country,isp\n")
+
+	_, _, err = s.Exec(":set output bogus")
+	ast.Equal(errors.ErrUnsupportedOutput, err)
+	_, _, err = s.Exec(":set hybrid-mode bogus")
+	ast.Equal(errors.ErrInvalidShellSetting, err)
+	_, _, err = s.Exec(":set lang")
+	ast.Equal(errors.ErrInvalidShellSetting, err)
+	_, _, err = s.Exec(":nope")
+	ast.Equal(errors.ErrUnknownShellCommand, err)
+	ast.Equal("table", m.Conf.OutputType)
+
+	_, quit, err = s.Exec(":quit")
+	ast.Nil(err)
+	ast.True(quit)
+}
+
+func TestShellSetProfiles(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	pm := m.profiles.get("cached", func() *Manager { return m.newProfileManager("cached", &Profile{}) })
+	_, err := pm.parseIP("200.1.1.1")
+	ast.Nil(err)
+	loaded := pm.ipv4.loaded()
+	_, err = m.parseIP("200.1.1.1")
+	ast.Nil(err)
+
+	// the settings reload the base readers only, the profiles keep their configuration
+	s := m.NewShell(&bytes.Buffer{})
+	_, _, err = s.Exec(":set fields country")
+	ast.Nil(err)
+	info, err := m.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal([]string{"中国"}, info.Values())
+	ast.Equal(loaded, pm.ipv4.loaded())
+	info, err = pm.parseIP("200.1.1.1")
+	ast.Nil(err)
+	ast.Equal([]string{"中国", "深圳"}, info.Values())
+}
+
+func TestShellRun(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	m.Conf.TextFormat = "%origin [%values] "
+	out := &bytes.Buffer{}
+	input := strings.Join([]string{
+		"200.1.1.1",
+		"",
+		":set output csv",
+		"130.0.0.1",
+		":bogus",
+		":quit",
+		"1.1.1.1",
+	}, "\n")
+	ast.Nil(m.NewShell(out).Run(strings.NewReader(input)))
+	ast.Equal("200.1.1.1 [中国] \nip,net,country\n130.0.0.1,128.0.0.0/2,美国\nerror: unknown shell command\n", out.String())
+}
+
+func TestIsShellCommand(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.True(isShellCommand(":set"))
+	ast.True(isShellCommand(":q"))
+	ast.False(isShellCommand("::1"))
+	ast.False(isShellCommand("::ffff:1.2.3.4"))
+	ast.False(isShellCommand(":"))
+	ast.False(isShellCommand("1.1.1.1"))
+}
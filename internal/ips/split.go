@@ -0,0 +1,347 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/format"
+	"github.com/sjzar/ips/format/ipdb"
+	"github.com/sjzar/ips/format/mmdb"
+	"github.com/sjzar/ips/format/plain"
+	"github.com/sjzar/ips/format/ruleset"
+	"github.com/sjzar/ips/internal/ipio"
+	"github.com/sjzar/ips/internal/operate"
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// SplitWriterFormat is the format of the writer splitting the networks into the writers of the values.
+const SplitWriterFormat = "split"
+
+// SplitUnknownName is the file name of the networks without the value.
+const SplitUnknownName = "unknown"
+
+// SplitOption is the option of splitting a database.
+type SplitOption struct {
+	// By is the field splitting the networks.
+	By string
+
+	// OutputDir is the directory of the output files.
+	OutputDir string
+
+	// OutputFormat is the format of the output files. Defaults to plain.
+	OutputFormat string
+
+	// NameMap is the file mapping the values to the file names, of "value=name" lines.
+	// The values not in the file are named by themselves.
+	NameMap string
+
+	// Where is the condition of the networks to split, in the syntax of the search, e.g. "country=中国".
+	Where string
+}
+
+// Split walks the database once, and streams the networks of each value of the field into the writer of its file
+// in the output directory. The adjacent networks of the same values are aggregated for the plain format.
+func (m *Manager) Split(_format, file []string, option SplitOption) error {
+	if len(option.By) == 0 {
+		return errors.ErrFieldInvalid
+	}
+	if len(option.OutputFormat) == 0 {
+		option.OutputFormat = plain.DBFormat
+	}
+	if len(_format) == 0 {
+		_format = make([]string, len(file))
+	} else if len(file) != len(_format) {
+		return errors.ErrInvalidFormat
+	}
+
+	var rule *operate.FieldSelectorRule
+	if len(option.Where) != 0 {
+		values, err := url.ParseQuery(option.Where)
+		if err != nil || len(values) == 0 {
+			log.Debug("url.ParseQuery error: ", option.Where, err)
+			return errors.ErrInvalidCondition
+		}
+		rule = &operate.FieldSelectorRule{Condition: values}
+	}
+
+	names, err := loadNameMap(option.NameMap)
+	if err != nil {
+		return err
+	}
+
+	reader, err := m.createReader(_format, file, true)
+	if err != nil {
+		log.Debug("m.createReader error: ", err)
+		return err
+	}
+	defer reader.Close()
+
+	if !reader.Meta().SupportFields()[option.By] {
+		return errors.ErrFieldInvalid
+	}
+	if err := util.PrepareDir(option.OutputDir); err != nil {
+		log.Debug("util.PrepareDir error: ", err)
+		return err
+	}
+
+	splitter := &splitWriter{
+		m:      m,
+		meta:   reader.Meta(),
+		option: option,
+		rule:   rule,
+		names:  names,
+		files:  make(map[string]*splitFile),
+		paths:  make(map[string]string),
+	}
+	// the plain files are aggregated as the networks arrive, which needs them in ascending order
+	readerJobs := m.Conf.ReaderJobs
+	if option.OutputFormat == plain.DBFormat {
+		readerJobs = 1
+	}
+	if err := ipio.NewStandardDumper(reader, splitter).Dump(readerJobs); err != nil {
+		log.Debug("dumper.Dump error: ", err)
+		return err
+	}
+
+	return splitter.writeFiles()
+}
+
+// splitWriter is a writer of the dumper, inserting the IP information into the writer of its value.
+type splitWriter struct {
+	m      *Manager
+	meta   *model.Meta
+	option SplitOption
+	rule   *operate.FieldSelectorRule
+	names  map[string]string
+	files  map[string]*splitFile
+
+	// paths maps the paths of the files to their values, to detect the values sharing a file.
+	paths map[string]string
+}
+
+// splitFile is an output file of the split.
+// The IP information of a plain writer is held until the next network of the file arrives,
+// so that the adjacent networks of the same values are aggregated.
+type splitFile struct {
+	path    string
+	writer  format.Writer
+	pending *model.IPInfo
+}
+
+// SetOption is not supported by the splitter.
+func (s *splitWriter) SetOption(_ interface{}) error {
+	return nil
+}
+
+// Insert adds the IP information to the file of its value, creating the file writer on the first value.
+func (s *splitWriter) Insert(info *model.IPInfo) error {
+	if s.rule != nil && !s.rule.IsMatch(info) {
+		return nil
+	}
+
+	value, _ := info.GetData(s.option.By)
+	file, ok := s.files[value]
+	if !ok {
+		name := s.fileName(value) + splitExt(s.option.OutputFormat)
+		if !validFileName(name) {
+			log.Debugf("invalid file name %q of %q", name, value)
+			return errors.ErrInvalidSplitFile
+		}
+		path := filepath.Join(s.option.OutputDir, name)
+		if prev, ok := s.paths[path]; ok {
+			log.Debugf("values %q and %q are split into the same file %s", prev, value, path)
+			return errors.ErrDuplicateSplitFile
+		}
+		s.paths[path] = value
+		writer, err := format.NewWriter(s.option.OutputFormat, path, s.meta)
+		if err != nil {
+			log.Debug("format.NewWriter error: ", err)
+			return err
+		}
+		if err := s.m.setWriterOption(writer, nil); err != nil {
+			return err
+		}
+		file = &splitFile{path: path, writer: writer}
+		s.files[value] = file
+	}
+
+	if _, ok := file.writer.(*plain.Writer); ok {
+		return file.aggregate(info)
+	}
+	return file.writer.Insert(info)
+}
+
+// WriteTo is not supported by the splitter, the files are written by writeFiles.
+func (s *splitWriter) WriteTo(_ io.Writer) (int64, error) {
+	return 0, nil
+}
+
+// WriterFormat returns the output format, so that the dumper reads as for the output files.
+func (s *splitWriter) WriterFormat() string {
+	return s.option.OutputFormat
+}
+
+// fileName returns the file name of the value, by the name map or the value itself.
+// The path separators are replaced, and the empty value is named SplitUnknownName.
+// Different values may get the same name, which Insert rejects.
+func (s *splitWriter) fileName(value string) string {
+	if name, ok := s.names[value]; ok {
+		return name
+	}
+	if len(value) == 0 {
+		return SplitUnknownName
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", string(os.PathSeparator), "_").Replace(value)
+}
+
+// validFileName reports whether the name is a file in the output directory,
+// neither "." nor ".." nor a path of other directories.
+func validFileName(name string) bool {
+	name = filepath.Clean(name)
+	return name != "." && name != ".." && !strings.ContainsAny(name, "/\\"+string(os.PathSeparator))
+}
+
+// writeFiles writes the files in the order of their paths, each replacing the existing file after validated.
+func (s *splitWriter) writeFiles() error {
+	files := make([]*splitFile, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+
+	for _, file := range files {
+		if err := file.flush(); err != nil {
+			return err
+		}
+		if err := file.write(s.option.OutputFormat); err != nil {
+			return err
+		}
+	}
+	log.Infof("split %d files into %s", len(files), s.option.OutputDir)
+	return nil
+}
+
+// aggregate joins the network to the pending one if they are adjacent with the same values,
+// or inserts the pending one and holds the network instead. The networks arrive in ascending order.
+func (f *splitFile) aggregate(info *model.IPInfo) error {
+	if f.pending != nil && slices.Equal(f.pending.Values(), info.Values()) && f.pending.IPNet.Join(info.IPNet) {
+		return nil
+	}
+	if err := f.flush(); err != nil {
+		return err
+	}
+	f.pending = info
+	return nil
+}
+
+// flush inserts the pending IP information.
+func (f *splitFile) flush() error {
+	if f.pending == nil {
+		return nil
+	}
+	info := f.pending
+	f.pending = nil
+	return f.writer.Insert(info)
+}
+
+// write writes the file atomically.
+func (f *splitFile) write(_format string) error {
+	atomicFile, err := util.CreateAtomicFile(f.path)
+	if err != nil {
+		log.Debug("util.CreateAtomicFile error: ", err)
+		return err
+	}
+	defer func() {
+		_ = atomicFile.Abort()
+	}()
+
+	if _, err := f.writer.WriteTo(atomicFile.File); err != nil {
+		log.Debug("writer.WriteTo error: ", err)
+		return err
+	}
+	if err := atomicFile.Commit(func(name string) error {
+		return validateDatabase(_format, name)
+	}); err != nil {
+		log.Debug("atomicFile.Commit error: ", err)
+		return err
+	}
+	return nil
+}
+
+// splitExt returns the file extension of the output format.
+func splitExt(_format string) string {
+	switch _format {
+	case plain.DBFormat:
+		return plain.DBExt
+	case ipdb.DBFormat:
+		return ipdb.DBExt
+	case mmdb.DBFormat:
+		return mmdb.DBExt
+	}
+	if ext, ok := ruleset.Exts[_format]; ok {
+		return ext
+	}
+	return ""
+}
+
+// loadNameMap loads the file of "value=name" lines mapping the values to the file names.
+// Empty lines and lines starting with "#" are skipped.
+func loadNameMap(file string) (map[string]string, error) {
+	names := make(map[string]string)
+	if len(file) == 0 {
+		return names, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		log.Debug("os.Open error: ", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value, name, ok := strings.Cut(line, "=")
+		if !ok || len(strings.TrimSpace(name)) == 0 {
+			return nil, errors.ErrInvalidFormat
+		}
+		names[strings.TrimSpace(value)] = strings.TrimSpace(name)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Debug("scanner.Err: ", err)
+		return nil, err
+	}
+	return names, nil
+}
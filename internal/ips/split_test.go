@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestSplit(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	m.Conf.DPFields = "country,city"
+	dir := filepath.Join(t.TempDir(), "out")
+	ast.Nil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{By: "country", OutputDir: dir}))
+
+	entries, err := os.ReadDir(dir)
+	ast.Nil(err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	ast.Equal([]string{"中国.txt", "保留.txt", "美国.txt"}, names)
+
+	data, err := os.ReadFile(filepath.Join(dir, "中国.txt"))
+	ast.Nil(err)
+	ast.True(strings.HasSuffix(string(data), "192.0.0.0/3\t中国,深圳\n224.0.0.0/3\t中国,广州\n"))
+
+	// aggregated by the dump fields
+	m.Conf.DPFields = "country"
+	ast.Nil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{By: "country", OutputDir: dir}))
+	data, err = os.ReadFile(filepath.Join(dir, "中国.txt"))
+	ast.Nil(err)
+	ast.True(strings.HasSuffix(string(data), "\n192.0.0.0/2\t中国\n"))
+}
+
+func TestSplitWhere(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	dir := t.TempDir()
+	nameMap := filepath.Join(dir, "names.txt")
+	ast.Nil(os.WriteFile(nameMap, []byte("# cities\n深圳=sz\n"), 0644))
+
+	out := filepath.Join(dir, "out")
+	ast.Nil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{
+		By:           "city",
+		OutputDir:    out,
+		OutputFormat: "haproxy",
+		NameMap:      nameMap,
+		Where:        "country=中国",
+	}))
+	entries, err := os.ReadDir(out)
+	ast.Nil(err)
+	ast.Len(entries, 2)
+	data, err := os.ReadFile(filepath.Join(out, "sz.map"))
+	ast.Nil(err)
+	ast.True(strings.HasSuffix(string(data), "192.0.0.0/3 中国\n"))
+	_, err = os.Stat(filepath.Join(out, "广州.map"))
+	ast.Nil(err)
+
+	// values sharing a file are rejected instead of overwriting each other
+	ast.Nil(os.WriteFile(nameMap, []byte("深圳=广州\n"), 0644))
+	conflict := filepath.Join(dir, "conflict")
+	ast.Equal(errors.ErrDuplicateSplitFile, m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{
+		By:        "city",
+		OutputDir: conflict,
+		NameMap:   nameMap,
+		Where:     "country=中国",
+	}))
+	entries, err = os.ReadDir(conflict)
+	ast.Nil(err)
+	ast.Empty(entries)
+
+	// names out of the output directory are rejected
+	for _, name := range []string{"../sz", "a/b", "a/.."} {
+		ast.Nil(os.WriteFile(nameMap, []byte("深圳="+name+"\n"), 0644))
+		ast.Equal(errors.ErrInvalidSplitFile, m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{
+			By:           "city",
+			OutputDir:    conflict,
+			OutputFormat: "mmdb",
+			NameMap:      nameMap,
+		}), name)
+	}
+	ast.True(validFileName("sz.txt"))
+	ast.True(validFileName("..sz"))
+	ast.False(validFileName(".."))
+	ast.False(validFileName("."))
+	ast.False(validFileName("a/../.."))
+	ast.False(validFileName("a\\b"))
+
+	ast.NotNil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{By: "unknown", OutputDir: out}))
+	ast.NotNil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{By: "city", OutputDir: out, Where: "%zz"}))
+	ast.NotNil(m.Split(m.Conf.IPv4Format, m.Conf.IPv4File, SplitOption{By: "city", OutputDir: out, OutputFormat: "unknown"}))
+}
@@ -0,0 +1,339 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/errors"
+	"github.com/sjzar/ips/pkg/model"
+)
+
+const (
+	// StatsFieldSep separates the fields of a combined group, e.g. "country+province".
+	StatsFieldSep = "+"
+
+	// StatsOthers is the value of the row counting the values beyond the top N.
+	StatsOthers = "(others)"
+)
+
+// StatsOption is the option of the statistics.
+type StatsOption struct {
+	// GroupBy is the groups of the statistics, separated by commas, and the fields of a group combined by "+",
+	// e.g. "country,country+province,isp". Defaults to each field of the results.
+	GroupBy string
+
+	// Top is the number of the most frequent values kept in each group, others are counted as one row.
+	// All the values are kept if not positive.
+	Top int
+}
+
+// Stats is the distribution of the IP information.
+type Stats struct {
+	// Total is the number of the IP addresses.
+	Total int `json:"total"`
+
+	// Unknown is the number of the IP addresses without any field value.
+	Unknown int `json:"unknown"`
+
+	// Private is the number of the private, loopback and link-local addresses.
+	Private int `json:"private"`
+
+	// Groups is the distributions by the groups.
+	Groups []*StatsGroup `json:"groups"`
+}
+
+// StatsGroup is the distribution of the values of the fields.
+type StatsGroup struct {
+	// Fields is the fields of the group.
+	Fields []string `json:"fields"`
+
+	// Items is the values and their counts, the most frequent first.
+	Items []*StatsItem `json:"items"`
+
+	// Others is the number of the IP addresses beyond the top N values.
+	Others int `json:"others"`
+}
+
+// StatsItem is the count of the values of the fields.
+type StatsItem struct {
+	Values  []string `json:"values"`
+	Count   int      `json:"count"`
+	Percent float64  `json:"percent"`
+}
+
+// statsState accumulates the IP information of ParseText when the statistics is enabled.
+type statsState struct {
+	mu     sync.Mutex
+	option StatsOption
+	groups [][]string
+	stats  Stats
+	counts []map[string]int
+}
+
+// EnableStats enables the statistics, the IP information of ParseText are accumulated
+// instead of being output, until FlushStats.
+// The statistics are output as a table, JSON or CSV.
+// The fields of the groups must be the output fields of the lookups, otherwise ErrFieldInvalid is returned.
+func (m *Manager) EnableStats(option StatsOption) error {
+	switch m.Conf.OutputType {
+	case "", OutputTypeText, OutputTypeTable, OutputTypeJSON, OutputTypeCSV:
+	default:
+		return errors.ErrUnsupportedOutput
+	}
+
+	state := &statsState{option: option}
+	for _, group := range strings.Split(option.GroupBy, ",") {
+		fields := make([]string, 0)
+		for _, field := range strings.Split(group, StatsFieldSep) {
+			if field = strings.TrimSpace(field); len(field) != 0 {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) != 0 {
+			state.groups = append(state.groups, fields)
+		}
+	}
+
+	// a field out of the results would count every IP address as empty
+	if len(state.groups) != 0 {
+		outputFields, err := m.outputFields()
+		if err != nil {
+			return err
+		}
+		valid := make(map[string]bool, len(outputFields))
+		for _, field := range outputFields {
+			valid[field] = true
+		}
+		for _, fields := range state.groups {
+			for _, field := range fields {
+				if !valid[field] {
+					log.Debugf("group-by field %s is not in the output fields %v", field, outputFields)
+					return errors.ErrFieldInvalid
+				}
+			}
+		}
+	}
+
+	m.stats = state
+	return nil
+}
+
+// addStats accumulates the IP information into the statistics.
+func (m *Manager) addStats(data []interface{}) {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	for _, info := range data {
+		v, ok := info.(*model.IPInfo)
+		if !ok {
+			continue
+		}
+		m.stats.add(v, m.Conf.UseDBFields)
+	}
+}
+
+// add counts the IP information in the total, unknown, private and each group.
+func (s *statsState) add(info *model.IPInfo, dbFields bool) {
+	output := info.Output(dbFields)
+	if s.counts == nil {
+		if len(s.groups) == 0 {
+			for _, field := range info.OutputFields(dbFields) {
+				s.groups = append(s.groups, []string{field})
+			}
+		}
+		s.counts = make([]map[string]int, len(s.groups))
+		for i := range s.counts {
+			s.counts[i] = make(map[string]int)
+		}
+	}
+
+	s.stats.Total++
+	unknown := true
+	for _, value := range output.Data {
+		if len(value) != 0 {
+			unknown = false
+			break
+		}
+	}
+	if unknown {
+		s.stats.Unknown++
+	}
+	if addr, err := netip.ParseAddr(output.IP); err == nil {
+		addr = addr.Unmap()
+		if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+			s.stats.Private++
+		}
+	}
+
+	for i, fields := range s.groups {
+		values := make([]string, len(fields))
+		for j, field := range fields {
+			values[j] = output.Data[field]
+		}
+		// values are joined by NUL, which is not in the field values
+		s.counts[i][strings.Join(values, "\x00")]++
+	}
+}
+
+// result returns the statistics, the items of each group sorted by the count and the value, and limited to the top N.
+func (s *statsState) result() *Stats {
+	stats := &Stats{
+		Total:   s.stats.Total,
+		Unknown: s.stats.Unknown,
+		Private: s.stats.Private,
+		Groups:  make([]*StatsGroup, 0, len(s.groups)),
+	}
+	for i, fields := range s.groups {
+		group := &StatsGroup{Fields: fields, Items: make([]*StatsItem, 0)}
+		if i < len(s.counts) {
+			for key, count := range s.counts[i] {
+				group.Items = append(group.Items, &StatsItem{
+					Values:  strings.Split(key, "\x00"),
+					Count:   count,
+					Percent: percent(count, stats.Total),
+				})
+			}
+		}
+		sort.Slice(group.Items, func(a, b int) bool {
+			if group.Items[a].Count != group.Items[b].Count {
+				return group.Items[a].Count > group.Items[b].Count
+			}
+			return strings.Join(group.Items[a].Values, "\x00") < strings.Join(group.Items[b].Values, "\x00")
+		})
+		if s.option.Top > 0 && len(group.Items) > s.option.Top {
+			for _, item := range group.Items[s.option.Top:] {
+				group.Others += item.Count
+			}
+			group.Items = group.Items[:s.option.Top]
+		}
+		stats.Groups = append(stats.Groups, group)
+	}
+	return stats
+}
+
+// FlushStats returns the statistics in the output type, and resets them.
+// It returns an empty string if the statistics is not enabled.
+func (m *Manager) FlushStats() (string, error) {
+	if m.stats == nil {
+		return "", nil
+	}
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+
+	stats := m.stats.result()
+	m.stats.stats = Stats{}
+	m.stats.counts = nil
+
+	switch m.Conf.OutputType {
+	case OutputTypeJSON:
+		return stats.json(m.Conf.JsonIndent)
+	case OutputTypeCSV:
+		return stats.csv()
+	default:
+		return stats.table(), nil
+	}
+}
+
+// rows returns the statistics in rows of the field, the value, the count and the percent,
+// the total, unknown and private counts first.
+func (s *Stats) rows() [][]string {
+	rows := [][]string{
+		{"total", "", strconv.Itoa(s.Total), formatPercent(percent(s.Total, s.Total))},
+		{"unknown", "", strconv.Itoa(s.Unknown), formatPercent(percent(s.Unknown, s.Total))},
+		{"private", "", strconv.Itoa(s.Private), formatPercent(percent(s.Private, s.Total))},
+	}
+	for _, group := range s.Groups {
+		field := strings.Join(group.Fields, StatsFieldSep)
+		for _, item := range group.Items {
+			rows = append(rows, []string{field, strings.Join(item.Values, StatsFieldSep), strconv.Itoa(item.Count), formatPercent(item.Percent)})
+		}
+		if group.Others > 0 {
+			rows = append(rows, []string{field, StatsOthers, strconv.Itoa(group.Others), formatPercent(percent(group.Others, s.Total))})
+		}
+	}
+	return rows
+}
+
+// json returns the statistics in JSON.
+func (s *Stats) json(indent bool) (string, error) {
+	var ret []byte
+	var err error
+	if indent {
+		ret, err = json.MarshalIndent(s, "", "  ")
+	} else {
+		ret, err = json.Marshal(s)
+	}
+	if err != nil {
+		log.Debug("json.Marshal error: ", err)
+		return "", err
+	}
+	return string(ret) + "\n", nil
+}
+
+// csv returns the statistics in CSV, with a header row.
+func (s *Stats) csv() (string, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	_ = w.Write(statsHeader)
+	_ = w.WriteAll(s.rows())
+	if err := w.Error(); err != nil {
+		log.Debug("csv.Writer.WriteAll error: ", err)
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// table returns the statistics in a table.
+func (s *Stats) table() string {
+	writer := &strings.Builder{}
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader(statsHeader)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.AppendBulk(s.rows())
+	table.Render()
+	return writer.String()
+}
+
+// statsHeader is the header of the statistics in a table or CSV.
+var statsHeader = []string{"field", "value", "count", "percent"}
+
+// percent returns the percent of the count in the total, rounded to two decimal places.
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*10000/float64(total)) / 100
+}
+
+// formatPercent formats the percent with two decimal places.
+func formatPercent(p float64) string {
+	return fmt.Sprintf("%.2f%%", p)
+}
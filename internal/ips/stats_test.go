@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestStats(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city,isp")
+	m.Conf.OutputType = OutputTypeCSV
+	ast.Nil(m.EnableStats(StatsOption{GroupBy: "country, country+city,isp", Top: 2}))
+
+	ret, err := m.ParseText("10.0.0.1 200.1.1.1 example.com")
+	ast.Nil(err)
+	ast.Equal("", ret)
+	_, err = m.ParseText("230.1.1.1 230.2.2.2 130.1.1.1")
+	ast.Nil(err)
+
+	ret, err = m.FlushStats()
+	ast.Nil(err)
+	ast.Equal(`field,value,count,percent
+total,,5,100.00%
+unknown,,0,0.00%
+private,,1,20.00%
+country,中国,3,60.00%
+country,保留,1,20.00%
+country,(others),1,20.00%
+country+city,中国+广州,2,40.00%
+country+city,中国+深圳,1,20.00%
+country+city,(others),2,40.00%
+isp,,4,80.00%
+isp,电信,1,20.00%
+`, ret)
+
+	// reset after flush
+	ret, err = m.FlushStats()
+	ast.Nil(err)
+	ast.Contains(ret, "total,,0,0.00%")
+
+	m.Conf.OutputType = OutputTypeJSON
+	_, err = m.ParseText("200.1.1.1")
+	ast.Nil(err)
+	ret, err = m.FlushStats()
+	ast.Nil(err)
+	ast.Equal(`{"total":1,"unknown":0,"private":0,"groups":[{"fields":["country"],"items":[{"values":["中国"],"count":1,"percent":100}],"others":0},{"fields":["country","city"],"items":[{"values":["中国","深圳"],"count":1,"percent":100}],"others":0},{"fields":["isp"],"items":[{"values":["电信"],"count":1,"percent":100}],"others":0}]}
+`, ret)
+}
+
+func TestStatsDefaultGroups(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	ast.Nil(m.EnableStats(StatsOption{}))
+	_, err := m.ParseText("200.1.1.1 1.1.1.1")
+	ast.Nil(err)
+	stats := m.stats.result()
+	ast.Equal(2, stats.Total)
+	ast.Len(stats.Groups, 2)
+	ast.Equal([]string{"country"}, stats.Groups[0].Fields)
+	ast.Equal([]string{"city"}, stats.Groups[1].Fields)
+
+	ret, err := m.FlushStats()
+	ast.Nil(err)
+	ast.Contains(ret, "| country | 中国  |     1 | 50.00%  |")
+
+	m.Conf.OutputType = OutputTypeYAML
+	ast.NotNil(m.EnableStats(StatsOption{}))
+}
+
+func TestStatsInvalidGroup(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city")
+	ast.Equal(errors.ErrFieldInvalid, m.EnableStats(StatsOption{GroupBy: "country+isp"}))
+	ast.Equal(errors.ErrFieldInvalid, m.EnableStats(StatsOption{GroupBy: "bogus"}))
+	ast.Nil(m.stats)
+	ast.Nil(m.EnableStats(StatsOption{GroupBy: "city,country+city"}))
+}
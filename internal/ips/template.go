@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/pkg/model"
+)
+
+// TemplateFuncs are the helper functions of the text templates.
+var TemplateFuncs = template.FuncMap{
+	// default returns the default value if the value is empty or missing, e.g. {{.city | default "-"}}
+	"default": func(def string, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+
+	// upper returns the value in upper case.
+	"upper": func(value interface{}) string {
+		return strings.ToUpper(fmt.Sprint(value))
+	},
+
+	// join joins the values with the separator, e.g. {{join "," .Values}}
+	"join": func(sep string, values []string) string {
+		return strings.Join(values, sep)
+	},
+
+	// cidr returns the network of the IP with the prefix length, e.g. {{cidr 24 .IP}}
+	// An empty value, e.g. the missing IP of a domain, returns empty.
+	"cidr": func(bits int, ip interface{}) (string, error) {
+		if ip == nil || ip == "" {
+			return "", nil
+		}
+		addr, err := netip.ParseAddr(fmt.Sprint(ip))
+		if err != nil {
+			return "", err
+		}
+		prefix, err := addr.Unmap().Prefix(bits)
+		if err != nil {
+			return "", err
+		}
+		return prefix.String(), nil
+	},
+}
+
+// textTemplate is the text template of the output, parsed on first use.
+type textTemplate struct {
+	once sync.Once
+	tmpl *template.Template
+	err  error
+}
+
+// textTemplate returns the parsed text template of the configuration, or nil if not configured.
+func (m *Manager) textTemplate() (*template.Template, error) {
+	if len(m.Conf.TextTemplate) == 0 {
+		return nil, nil
+	}
+	m.template.once.Do(func() {
+		m.template.tmpl, m.template.err = template.New("text").Funcs(TemplateFuncs).Parse(m.Conf.TextTemplate)
+		if m.template.err != nil {
+			log.Debug("template.Parse error: ", m.template.err)
+		}
+	})
+	return m.template.tmpl, m.template.err
+}
+
+// executeTemplate executes the text template with the data.
+func executeTemplate(tmpl *template.Template, data map[string]interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		log.Debug("template.Execute error: ", err)
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ipInfoTemplateData returns the data of the IP information for the text template.
+// The fields are accessed by their output names, along with the IP, the network, the values and the IPInfo itself.
+func (m *Manager) ipInfoTemplateData(info *model.IPInfo) map[string]interface{} {
+	output := info.Output(m.Conf.UseDBFields)
+	data := make(map[string]interface{}, len(output.Data)+4)
+	for field, value := range output.Data {
+		data[field] = value
+	}
+	data["IP"] = output.IP
+	data["Net"] = output.Net
+	data["Values"] = info.Values()
+	data["Info"] = info
+	return data
+}
+
+// domainInfoTemplateData returns the data of the domain information for the text template.
+// The fields are accessed by their names, along with the domain, the main domain, the values and the DomainInfo itself.
+func (m *Manager) domainInfoTemplateData(info *model.DomainInfo) map[string]interface{} {
+	data := make(map[string]interface{}, len(info.Data)+4)
+	for field, value := range info.Data {
+		data[field] = value
+	}
+	data["Domain"] = info.Domain
+	data["MainDomain"] = info.MainDomain
+	data["Values"] = info.Values()
+	data["Info"] = info
+	return data
+}
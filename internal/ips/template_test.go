@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextTemplate(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country,city,isp")
+	m.Conf.TextTemplate = `{{.IP}} {{.country}}{{if .city}}/{{.city}}{{end}} {{.isp | default "-"}} {{.Net}} {{cidr 24 .IP}} {{join "," .Values}}`
+
+	ret, err := m.ParseText("a 200.1.1.1 b")
+	ast.Nil(err)
+	ast.Equal("a 200.1.1.1 中国/深圳 电信 192.0.0.0/3 200.1.1.0/24 中国,深圳,电信 b", ret)
+
+	ret, err = m.ParseText("230.1.1.1")
+	ast.Nil(err)
+	ast.Equal("230.1.1.1 中国/广州 - 224.0.0.0/3 230.1.1.0/24 中国,广州,", ret)
+
+	ret, err = m.ParseText("1.1.1.1")
+	ast.Nil(err)
+	ast.Equal("1.1.1.1 保留 - 0.0.0.0/1 1.1.1.0/24 保留,,", ret)
+}
+
+func TestTextTemplateError(t *testing.T) {
+	ast := assert.New(t)
+
+	m := newTestManager(t, "country")
+	m.Conf.TextTemplate = `{{.IP`
+	_, err := m.ParseText("1.1.1.1")
+	ast.NotNil(err)
+
+	m = newTestManager(t, "country")
+	m.Conf.TextTemplate = `{{cidr 40 .IP}}`
+	_, err = m.ParseText("1.1.1.1")
+	ast.NotNil(err)
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	ast := assert.New(t)
+
+	def := TemplateFuncs["default"].(func(string, interface{}) interface{})
+	ast.Equal("-", def("-", nil))
+	ast.Equal("-", def("-", ""))
+	ast.Equal("a", def("-", "a"))
+
+	upper := TemplateFuncs["upper"].(func(interface{}) string)
+	ast.Equal("CN", upper("cn"))
+
+	cidr := TemplateFuncs["cidr"].(func(int, interface{}) (string, error))
+	ret, err := cidr(32, "2001:db8::1")
+	ast.Nil(err)
+	ast.Equal("2001:db8::/32", ret)
+	ret, err = cidr(24, nil)
+	ast.Nil(err)
+	ast.Equal("", ret)
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// DatePlaceholderRegexp matches the date placeholders in download URLs.
+// A placeholder is a Go time layout wrapped in braces, e.g. {2006-01} or {20060102}.
+var DatePlaceholderRegexp = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Number of older releases to try when resolving a date template, by granularity of the placeholders.
+const (
+	LookbackDays   = 31
+	LookbackMonths = 3
+	LookbackYears  = 1
+)
+
+// ResolveURL resolves the date placeholders in _url and returns the candidate URLs, newest first.
+// The release published for the current period may not be available yet, so older periods are
+// also returned, e.g. "dbip-city-lite-{2006-01}.mmdb.gz" resolves to this month and the previous months.
+// A URL without placeholders is returned as is.
+func ResolveURL(_url string, now time.Time) []string {
+	dates := ResolveDates(_url, now)
+	ret := make([]string, 0, len(dates))
+	for _, t := range dates {
+		ret = append(ret, FormatURL(_url, t))
+	}
+	return ret
+}
+
+// ResolveDates returns the dates to try for the date placeholders in _url, newest first.
+// The number of dates depends on the granularity of the placeholders, see LookbackDays.
+// Only now is returned if _url has no placeholders.
+func ResolveDates(_url string, now time.Time) []time.Time {
+	matches := DatePlaceholderRegexp.FindAllStringSubmatch(_url, -1)
+	if len(matches) == 0 {
+		return []time.Time{now}
+	}
+
+	layouts := make([]string, 0, len(matches))
+	for _, match := range matches {
+		layouts = append(layouts, match[1])
+	}
+	layout := strings.Join(layouts, "")
+
+	now = now.UTC()
+	lookback, step := LookbackYears, func(t time.Time, n int) time.Time { return t.AddDate(-n, 0, 0) }
+	switch {
+	case strings.Contains(layout, "02"):
+		lookback, step = LookbackDays, func(t time.Time, n int) time.Time { return t.AddDate(0, 0, -n) }
+	case strings.Contains(layout, "01"), strings.Contains(layout, "Jan"):
+		// normalize to the first day of the month, to avoid AddDate overflow on the 31st
+		now = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		lookback, step = LookbackMonths, func(t time.Time, n int) time.Time { return t.AddDate(0, -n, 0) }
+	}
+
+	ret := make([]time.Time, 0, lookback+1)
+	for i := 0; i <= lookback; i++ {
+		ret = append(ret, step(now, i))
+	}
+
+	return ret
+}
+
+// FormatURL replaces the date placeholders in _url with the date t.
+func FormatURL(_url string, t time.Time) string {
+	return DatePlaceholderRegexp.ReplaceAllStringFunc(_url, func(s string) string {
+		return t.Format(s[1 : len(s)-1])
+	})
+}
+
+// Update checks the database files for new versions and downloads the changed ones.
+// Conditional requests are used, so unchanged files are not downloaded again unless force is true.
+// If files is empty, all files recorded in the manifest and all files of known download sources in the ips dir are checked.
+func (m *Manager) Update(files []string, force bool) error {
+
+	manifest, err := LoadManifest(m.Conf.IPSDir)
+	if err != nil {
+		log.Debug("LoadManifest error: ", err)
+		return err
+	}
+
+	if len(files) == 0 {
+		files = m.installedFiles(manifest)
+		if len(files) == 0 {
+			fmt.Println("No database files to update.")
+			return nil
+		}
+	}
+
+	updated, failed := 0, 0
+	for _, file := range files {
+		source, ok := m.DownloadSource(file)
+		if !ok {
+			// files downloaded from a custom url are updated from the same url
+			if entry, ok := manifest.Files[file]; ok {
+				source = &DownloadSource{Name: file, URLs: []string{entry.URL}}
+			}
+		}
+		if source == nil {
+			log.Errorf("Update %s failed: %s", file, errors.ErrFileNotFound)
+			failed++
+			continue
+		}
+
+		ok, err := m.downloadFile(source, !force)
+		if err != nil {
+			log.Errorf("Update %s failed: %s", file, err)
+			failed++
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+
+	fmt.Printf("%d updated, %d up to date, %d failed.\n", updated, len(files)-updated-failed, failed)
+	if failed > 0 {
+		return errors.ErrFailedDownload
+	}
+
+	return nil
+}
+
+// installedFiles returns the database files in the ips dir that can be updated.
+func (m *Manager) installedFiles(manifest *Manifest) []string {
+	set := make(map[string]struct{})
+	for file := range manifest.Files {
+		set[file] = struct{}{}
+	}
+	for _, source := range m.DownloadSources() {
+		if util.IsFileExist(filepath.Join(m.Conf.IPSDir, source.Name)) {
+			set[source.Name] = struct{}{}
+		}
+	}
+
+	files := make([]string, 0, len(set))
+	for file := range set {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
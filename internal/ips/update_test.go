@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDownloadServer serves a database file, answering 304 to the conditional requests of its ETag.
+// The responses of the first requests can be replaced by the fail status codes.
+type testDownloadServer struct {
+	mu       sync.Mutex
+	content  string
+	etag     string
+	fail     []int
+	requests []*http.Request
+}
+
+func (s *testDownloadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	if len(s.fail) > 0 {
+		code := s.fail[0]
+		s.fail = s.fail[1:]
+		w.WriteHeader(code)
+		return
+	}
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	w.Header().Set("Last-Modified", "Wed, 18 Oct 2023 00:00:00 GMT")
+	_, _ = w.Write([]byte(s.content))
+}
+
+// newTestDownloadManager creates a Manager downloading to a temporary ips dir.
+func newTestDownloadManager(t *testing.T, sources ...*DownloadSource) *Manager {
+	return NewManager(&Config{
+		IPSDir:          t.TempDir(),
+		DownloadSources: sources,
+	})
+}
+
+func TestResolveURL(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Date(2023, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	ast.Equal([]string{"https://foo.com/city.ipdb"}, ResolveURL("https://foo.com/city.ipdb", now))
+
+	ast.Equal([]string{
+		"https://foo.com/city-2023-03.mmdb.gz",
+		"https://foo.com/city-2023-02.mmdb.gz",
+		"https://foo.com/city-2023-01.mmdb.gz",
+		"https://foo.com/city-2022-12.mmdb.gz",
+	}, ResolveURL("https://foo.com/city-{2006-01}.mmdb.gz", now))
+
+	days := ResolveURL("https://foo.com/{20060102}/qqwry.dat", now)
+	ast.Len(days, LookbackDays+1)
+	ast.Equal("https://foo.com/20230331/qqwry.dat", days[0])
+	ast.Equal("https://foo.com/20230330/qqwry.dat", days[1])
+
+	ast.Equal([]string{
+		"https://foo.com/2023/city.ipdb",
+		"https://foo.com/2022/city.ipdb",
+	}, ResolveURL("https://foo.com/{2006}/city.ipdb", now))
+}
+
+func TestDownloadFileConditional(t *testing.T) {
+	ast := assert.New(t)
+
+	ts := &testDownloadServer{content: "v1", etag: `"v1"`}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	source := &DownloadSource{Name: "test.bin", URLs: []string{server.URL + "/test.bin"}}
+	m := newTestDownloadManager(t, source)
+	file := filepath.Join(m.Conf.IPSDir, "test.bin")
+
+	ok, err := m.downloadFile(source, true)
+	ast.Nil(err)
+	ast.True(ok)
+	data, err := os.ReadFile(file)
+	ast.Nil(err)
+	ast.Equal("v1", string(data))
+
+	// the manifest records the version of the file
+	manifest, err := LoadManifest(m.Conf.IPSDir)
+	ast.Nil(err)
+	entry := manifest.Files["test.bin"]
+	if ast.NotNil(entry) {
+		sum := sha256.Sum256([]byte("v1"))
+		ast.Equal(server.URL+"/test.bin", entry.URL)
+		ast.Equal(`"v1"`, entry.ETag)
+		ast.Equal("Wed, 18 Oct 2023 00:00:00 GMT", entry.LastModified)
+		ast.Equal(hex.EncodeToString(sum[:]), entry.SHA256)
+	}
+
+	// an unchanged file is not downloaded again
+	ok, err = m.downloadFile(source, true)
+	ast.Nil(err)
+	ast.False(ok)
+	ast.Len(ts.requests, 2)
+	ast.Equal(`"v1"`, ts.requests[1].Header.Get("If-None-Match"))
+	ast.Equal("Wed, 18 Oct 2023 00:00:00 GMT", ts.requests[1].Header.Get("If-Modified-Since"))
+
+	// a new version is downloaded by the update
+	ts.content, ts.etag = "v2", `"v2"`
+	ast.Nil(m.Update(nil, false))
+	data, err = os.ReadFile(file)
+	ast.Nil(err)
+	ast.Equal("v2", string(data))
+
+	// a locally modified file is downloaded without the conditional headers
+	ast.Nil(os.WriteFile(file, []byte("modified"), 0644))
+	ok, err = m.downloadFile(source, true)
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Empty(ts.requests[len(ts.requests)-1].Header.Get("If-None-Match"))
+
+	// force downloads without the conditional headers
+	ok, err = m.downloadFile(source, false)
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Empty(ts.requests[len(ts.requests)-1].Header.Get("If-None-Match"))
+}
+
+func TestDownloadFileMirror(t *testing.T) {
+	ast := assert.New(t)
+
+	primary := &testDownloadServer{content: "primary", etag: `"p"`, fail: []int{http.StatusInternalServerError}}
+	primaryServer := httptest.NewServer(primary)
+	defer primaryServer.Close()
+	mirror := &testDownloadServer{content: "mirror", etag: `"m"`}
+	mirrorServer := httptest.NewServer(mirror)
+	defer mirrorServer.Close()
+
+	source := &DownloadSource{Name: "test.bin", URLs: []string{primaryServer.URL + "/test.bin", mirrorServer.URL + "/test.bin"}}
+	m := newTestDownloadManager(t, source)
+
+	ok, err := m.downloadFile(source, false)
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Len(primary.requests, 1)
+	ast.Len(mirror.requests, 1)
+
+	data, err := os.ReadFile(filepath.Join(m.Conf.IPSDir, "test.bin"))
+	ast.Nil(err)
+	ast.Equal("mirror", string(data))
+	manifest, err := LoadManifest(m.Conf.IPSDir)
+	ast.Nil(err)
+	ast.Equal(mirrorServer.URL+"/test.bin", manifest.Files["test.bin"].URL)
+
+	// all the mirrors failing keeps the downloaded file
+	primary.fail = []int{http.StatusInternalServerError}
+	mirror.fail = []int{http.StatusBadGateway}
+	_, err = m.downloadFile(source, false)
+	ast.NotNil(err)
+	data, err = os.ReadFile(filepath.Join(m.Conf.IPSDir, "test.bin"))
+	ast.Nil(err)
+	ast.Equal("mirror", string(data))
+}
+
+func TestDownloadFileLookback(t *testing.T) {
+	ast := assert.New(t)
+
+	// the release of today is not published yet
+	ts := &testDownloadServer{content: "yesterday", etag: `"y"`, fail: []int{http.StatusNotFound}}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	source := &DownloadSource{Name: "test.bin", URLs: []string{server.URL + "/{20060102}/test.bin"}}
+	m := newTestDownloadManager(t, source)
+
+	ok, err := m.downloadFile(source, false)
+	ast.Nil(err)
+	ast.True(ok)
+	if ast.Len(ts.requests, 2) {
+		first, err := time.Parse("20060102", ts.requests[0].URL.Path[1:9])
+		ast.Nil(err)
+		second, err := time.Parse("20060102", ts.requests[1].URL.Path[1:9])
+		ast.Nil(err)
+		ast.Equal(first.AddDate(0, 0, -1), second)
+
+		manifest, err := LoadManifest(m.Conf.IPSDir)
+		ast.Nil(err)
+		ast.Equal(server.URL+ts.requests[1].URL.Path, manifest.Files["test.bin"].URL)
+	}
+
+	// no release within the lookback is not found
+	ts.fail = make([]int, LookbackDays+1)
+	for i := range ts.fail {
+		ts.fail[i] = http.StatusNotFound
+	}
+	_, err = m.downloadFile(source, false)
+	ast.NotNil(err)
+	ast.Empty(ts.fail)
+}
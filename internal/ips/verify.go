@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sjzar/ips/internal/util"
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+const (
+	SignatureMinisign = "minisign"
+	SignatureGPG      = "gpg"
+)
+
+// signatureVerifier verifies the detached signature of the content written to it.
+// The content is streamed to the verification goroutine, so the download is not buffered.
+// A verifier without a signature accepts any content.
+type signatureVerifier struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// newSignatureVerifier downloads the signature of the source for the given date
+// and returns a verifier of the downloaded content.
+func (s *DownloadSource) newSignatureVerifier(date time.Time) (*signatureVerifier, error) {
+	if len(s.SignatureURL) == 0 {
+		return &signatureVerifier{}, nil
+	}
+
+	_url := FormatURL(s.SignatureURL, date)
+	signature, err := s.get(_url)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var verify func(r io.Reader) error
+	switch s.signatureType(_url) {
+	case SignatureMinisign:
+		verify, err = minisignVerify(key, signature)
+	case SignatureGPG:
+		verify, err = gpgVerify(key, signature)
+	default:
+		err = errors.ErrUnsupportedSignature
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	v := &signatureVerifier{
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+	go func() {
+		err := verify(pr)
+		// drain the rest of the content, so the writer never blocks
+		_, _ = io.Copy(io.Discard, pr)
+		v.done <- err
+	}()
+
+	return v, nil
+}
+
+// Write feeds the downloaded content to the verifier.
+func (v *signatureVerifier) Write(p []byte) (int, error) {
+	if v.pw == nil {
+		return len(p), nil
+	}
+	return v.pw.Write(p)
+}
+
+// Verify checks the signature after all the content has been written.
+func (v *signatureVerifier) Verify() error {
+	if v.pw == nil {
+		return nil
+	}
+	_ = v.pw.Close()
+	return <-v.done
+}
+
+// Close stops the verification of an incomplete download.
+func (v *signatureVerifier) Close() {
+	if v.pw != nil {
+		_ = v.pw.CloseWithError(io.ErrUnexpectedEOF)
+	}
+}
+
+// signatureType returns the configured signature type, or detects it by the signature url.
+func (s *DownloadSource) signatureType(_url string) string {
+	if len(s.SignatureType) != 0 {
+		return strings.ToLower(s.SignatureType)
+	}
+	if strings.HasSuffix(_url, ".minisig") {
+		return SignatureMinisign
+	}
+	return SignatureGPG
+}
+
+// publicKey returns the public key of the source, read from a file if PublicKey is a file path.
+func (s *DownloadSource) publicKey() ([]byte, error) {
+	if len(s.PublicKey) == 0 {
+		return nil, errors.ErrInvalidPublicKey
+	}
+	if util.IsFileExist(s.PublicKey) {
+		return os.ReadFile(s.PublicKey)
+	}
+	return []byte(s.PublicKey), nil
+}
+
+// minisignVerify returns a function verifying a minisign signature of the content.
+// Only prehashed signatures, the default since minisign 0.10, can be verified while streaming.
+func minisignVerify(key, signature []byte) (func(r io.Reader) error, error) {
+	var publicKey minisign.PublicKey
+	if err := publicKey.UnmarshalText(bytes.TrimSpace(key)); err != nil {
+		log.Debug("minisign.PublicKey.UnmarshalText error: ", err)
+		return nil, errors.ErrInvalidPublicKey
+	}
+
+	return func(r io.Reader) error {
+		reader := minisign.NewReader(r)
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return err
+		}
+		if !reader.Verify(publicKey, signature) {
+			return errors.ErrSignatureMismatch
+		}
+		return nil
+	}, nil
+}
+
+// gpgVerify returns a function verifying a detached gpg signature of the content.
+// Both the key and the signature may be armored or binary.
+func gpgVerify(key, signature []byte) (func(r io.Reader) error, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(key))
+	}
+	if err != nil {
+		log.Debug("openpgp.ReadKeyRing error: ", err)
+		return nil, errors.ErrInvalidPublicKey
+	}
+
+	check := openpgp.CheckDetachedSignature
+	if isArmored(signature) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+
+	return func(r io.Reader) error {
+		if _, err := check(keyring, r, bytes.NewReader(signature), nil); err != nil {
+			log.Debug("openpgp.CheckDetachedSignature error: ", err)
+			return errors.ErrSignatureMismatch
+		}
+		return nil
+	}, nil
+}
+
+// isArmored checks whether data is an ASCII armored OpenPGP block.
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
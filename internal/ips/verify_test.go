@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ips
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aead.dev/minisign"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	ast := assert.New(t)
+
+	content := []byte("1.0.0.0\t1.0.0.255\tAU\n")
+	signatures := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(signatures[r.URL.Path])
+	}))
+	defer server.Close()
+
+	verify := func(source *DownloadSource, data []byte) error {
+		v, err := source.newSignatureVerifier(time.Now())
+		if err != nil {
+			return err
+		}
+		defer v.Close()
+		if _, err := io.Copy(v, bytes.NewReader(data)); err != nil {
+			return err
+		}
+		return v.Verify()
+	}
+	tampered := []byte(strings.Replace(string(content), "AU", "CN", 1))
+
+	// minisign
+	publicKey, privateKey, err := minisign.GenerateKey(rand.Reader)
+	ast.Nil(err)
+	key, err := publicKey.MarshalText()
+	ast.Nil(err)
+	reader := minisign.NewReader(bytes.NewReader(content))
+	_, _ = io.Copy(io.Discard, reader)
+	signatures["/db.minisig"] = reader.Sign(privateKey)
+
+	source := &DownloadSource{SignatureURL: server.URL + "/db.minisig", PublicKey: string(key)}
+	ast.Nil(verify(source, content))
+	ast.Equal(errors.ErrSignatureMismatch, verify(source, tampered))
+
+	source.PublicKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	ast.Equal(errors.ErrSignatureMismatch, verify(source, content))
+	source.PublicKey = "invalid"
+	ast.Equal(errors.ErrInvalidPublicKey, verify(source, content))
+
+	// gpg
+	entity, err := openpgp.NewEntity("ips", "", "ips@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	ast.Nil(err)
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	ast.Nil(err)
+	ast.Nil(entity.Serialize(w))
+	ast.Nil(w.Close())
+	sig := &bytes.Buffer{}
+	ast.Nil(openpgp.ArmoredDetachSign(sig, entity, bytes.NewReader(content), nil))
+	signatures["/db.asc"] = sig.Bytes()
+
+	source = &DownloadSource{SignatureURL: server.URL + "/db.asc", PublicKey: buf.String()}
+	ast.Nil(verify(source, content))
+	ast.Equal(errors.ErrSignatureMismatch, verify(source, tampered))
+
+	// no signature
+	ast.Nil(verify(&DownloadSource{}, tampered))
+}
@@ -23,6 +23,7 @@ import (
 
 // Translator is a structure that provides functionality to translate IPInfo's geolocation fields.
 type Translator struct {
+	SourceLang string // Language of the data, the database language if empty
 	TargetLang string // Target language for translation
 }
 
@@ -44,13 +45,18 @@ func (t *Translator) Do(info *model.IPInfo) error {
 	// List of geolocation fields to be translated
 	fields := []string{model.Continent, model.Country, model.Province, model.City}
 
+	sourceLang := t.SourceLang
+	if len(sourceLang) == 0 {
+		sourceLang = geo.DatabaseLanguage
+	}
+
 	// Iterate through each field and translate it
 	for _, field := range fields {
 		if value, ok := info.Data[field]; ok {
-			info.Data[field] = geo.Translate(field, value)
+			info.Data[field] = geo.TranslateFrom(sourceLang, t.TargetLang, field, value)
 		} else if alias, ok := info.FieldAlias[field]; ok {
 			if value, ok := info.Data[alias]; ok {
-				info.Data[alias] = geo.Translate(field, value)
+				info.Data[alias] = geo.TranslateFrom(sourceLang, t.TargetLang, field, value)
 			}
 		}
 	}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2023 shenjunzheng@gmail.com
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sjzar/ips/pkg/errors"
+)
+
+// Archive types supported by OpenArchive.
+const (
+	ArchiveNone    = "none"
+	ArchiveGzip    = "gz"
+	ArchiveTar     = "tar"
+	ArchiveTarGzip = "tar.gz"
+	ArchiveZip     = "zip"
+)
+
+// ArchiveType detects the archive type by the suffix of the file name.
+func ArchiveType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGzip
+	case strings.HasSuffix(name, ".gz"):
+		return ArchiveGzip
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTar
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip
+	}
+	return ArchiveNone
+}
+
+// OpenArchive returns a reader of the file packed in the archive read from r.
+// For tar and zip archives, name is matched against the base names of the archived files,
+// and the first regular file is used if name is empty.
+// Closing the returned reader does not close r.
+func OpenArchive(r io.Reader, archive, name string) (io.ReadCloser, error) {
+	switch archive {
+	case "", ArchiveNone:
+		return io.NopCloser(r), nil
+	case ArchiveGzip:
+		return gzip.NewReader(r)
+	case ArchiveTar:
+		return openTar(r, name)
+	case ArchiveTarGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return openTar(gr, name)
+	case ArchiveZip:
+		return openZip(r, name)
+	}
+	return nil, errors.ErrUnsupportedArchive
+}
+
+// openTar seeks r to the named file in the tar archive.
+func openTar(r io.Reader, name string) (io.ReadCloser, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.ErrFileNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if len(name) == 0 || path.Base(hdr.Name) == name {
+			return io.NopCloser(tr), nil
+		}
+	}
+}
+
+// openZip buffers the zip archive to a temporary file, bcs zip needs random access,
+// and opens the named file in it. The temporary file is removed when the reader is closed.
+func openZip(r io.Reader, name string) (io.ReadCloser, error) {
+	f, err := os.CreateTemp("", "ips-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if len(name) == 0 || path.Base(file.Name) == name {
+			rc, err := file.Open()
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+			return &zipFileReader{ReadCloser: rc, cleanup: cleanup}, nil
+		}
+	}
+
+	cleanup()
+	return nil, errors.ErrFileNotFound
+}
+
+// zipFileReader removes the buffered zip archive when closed.
+type zipFileReader struct {
+	io.ReadCloser
+	cleanup func()
+}
+
+// Close closes the archived file and removes the buffered zip archive.
+func (z *zipFileReader) Close() error {
+	err := z.ReadCloser.Close()
+	z.cleanup()
+	return err
+}
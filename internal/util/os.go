@@ -17,7 +17,11 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"os"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 
@@ -51,3 +55,93 @@ func IsFileExist(file string) bool {
 	}
 	return fi.Mode().IsRegular()
 }
+
+// FileSHA256 returns the hex encoded sha256 checksum of the file.
+func FileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// AtomicFile is a file that is written to a temporary path in the same directory
+// as its destination, and only moved to the destination when committed.
+// Until then, any existing file at the destination is left untouched.
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomicFile creates a temporary file next to path.
+// The temporary file keeps the extension of path, so format detection by extension still works on it.
+func CreateAtomicFile(path string) (*AtomicFile, error) {
+	dir, base := filepath.Split(path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".*"+filepath.Ext(base))
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{
+		File: f,
+		path: path,
+	}, nil
+}
+
+// Commit flushes the temporary file to disk, validates it and renames it to the destination path.
+// validate is called with the temporary file path after it has been closed, it can be nil.
+// If any step fails, the temporary file is removed and the destination is left unchanged.
+func (f *AtomicFile) Commit(validate func(name string) error) error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	name := f.File.Name()
+	err := f.File.Sync()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && validate != nil {
+		err = validate(name)
+	}
+	if err == nil {
+		mode := os.FileMode(0644)
+		if fi, statErr := os.Stat(f.path); statErr == nil {
+			mode = fi.Mode().Perm()
+		}
+		err = os.Chmod(name, mode)
+	}
+	if err == nil {
+		err = os.Rename(name, f.path)
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+
+	return nil
+}
+
+// Abort closes and removes the temporary file. It is a no-op after Commit,
+// so it is safe to defer right after CreateAtomicFile.
+func (f *AtomicFile) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	_ = f.File.Close()
+	return os.Remove(f.File.Name())
+}
@@ -19,6 +19,7 @@ package ipnet
 import (
 	"math/bits"
 	"net"
+	"sort"
 )
 
 // Range represents an IP range with a start and end IP.
@@ -158,3 +159,23 @@ func (r Ranges) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
 
 // Less checks if the start IP of the range at index i is less than that at index j.
 func (r Ranges) Less(i, j int) bool { return IPLess(r[i].Start, r[j].Start) }
+
+// IPNets returns the minimal CIDR groups covering the IP ranges, joining the overlapping and adjacent ones.
+// The ranges are sorted in place, and IPv4 and IPv6 ranges are never joined.
+func (r Ranges) IPNets() []*net.IPNet {
+	sort.Sort(r)
+
+	merged := make([]*Range, 0)
+	for i := range r {
+		if n := len(merged); n > 0 && (merged[n-1].Start.To4() != nil) == (r[i].Start.To4() != nil) && merged[n-1].Join(&r[i]) {
+			continue
+		}
+		merged = append(merged, &Range{Start: r[i].Start.To16(), End: r[i].End.To16()})
+	}
+
+	var result []*net.IPNet
+	for _, m := range merged {
+		result = append(result, m.IPNets()...)
+	}
+	return result
+}
@@ -141,3 +141,21 @@ func TestRange_CommonRange(t *testing.T) {
 	// Result: Error ipr4 is not adjacent
 	ast.False(ipr1.CommonRange(ipr1.Start, ipr4))
 }
+
+func TestRangesIPNets(t *testing.T) {
+	ast := assert.New(t)
+
+	ranges := Ranges{
+		{Start: net.ParseIP("10.0.1.0"), End: net.ParseIP("10.0.1.255")},
+		{Start: net.ParseIP("10.0.0.0"), End: net.ParseIP("10.0.0.255")},
+		{Start: net.ParseIP("10.0.2.0"), End: net.ParseIP("10.0.2.127")},
+		{Start: net.ParseIP("10.0.0.128"), End: net.ParseIP("10.0.0.255")},
+		{Start: net.ParseIP("::"), End: net.ParseIP("::ffff")},
+	}
+	ret := make([]string, 0)
+	for _, ipNet := range ranges.IPNets() {
+		ret = append(ret, ipNet.String())
+	}
+	ast.Equal([]string{"::/112", "10.0.0.0/23", "10.0.2.0/25"}, ret)
+	ast.Empty(Ranges{}.IPNets())
+}
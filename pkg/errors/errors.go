@@ -33,6 +33,7 @@ var (
 	ErrMetaMissing            = errors.New("meta information missing")
 	ErrNilWriter              = errors.New("writer is not initialized")
 	ErrUnsupportedLanguage    = errors.New("unsupported language")
+	ErrNameTooLong            = errors.New("name is too long")
 	ErrKeyRequired            = errors.New("key is required for encrypted database, use `--database-option \"key=<your key>\"` or `--input-option \"key=<your key>\"` option to set")
 
 	// IPio
@@ -48,13 +49,36 @@ var (
 
 	// Command
 
-	ErrFileNotFound      = errors.New("file not found")
-	ErrFailedDownload    = errors.New("failed to download")
-	ErrInvalidDirectory  = errors.New("invalid directory path")
-	ErrMissingConfigName = errors.New("config name not specified")
-	ErrDiscoveryFailed   = errors.New("failed to discover IP address")
+	ErrFileNotFound         = errors.New("file not found")
+	ErrFailedDownload       = errors.New("failed to download")
+	ErrSourceNotFound       = errors.New("download source not found")
+	ErrUnsupportedArchive   = errors.New("unsupported archive type")
+	ErrChecksumMismatch     = errors.New("checksum mismatch")
+	ErrInvalidChecksum      = errors.New("invalid checksum file")
+	ErrSignatureMismatch    = errors.New("signature verification failed")
+	ErrUnsupportedSignature = errors.New("unsupported signature type")
+	ErrInvalidPublicKey     = errors.New("invalid public key")
+	ErrInvalidDirectory     = errors.New("invalid directory path")
+	ErrMissingConfigName    = errors.New("config name not specified")
+	ErrDiscoveryFailed      = errors.New("failed to discover IP address")
+	ErrUnsupportedOutput    = errors.New("unsupported output type")
+	ErrInvalidCondition     = errors.New("invalid search condition")
+	ErrUnknownShellCommand  = errors.New("unknown shell command")
+	ErrInvalidShellSetting  = errors.New("invalid shell setting")
+	ErrDuplicateSplitFile   = errors.New("different values split into the same file")
+	ErrInvalidSplitFile     = errors.New("invalid split file name")
 
 	// Server
 
-	ErrInvalidIP = errors.New("invalid IP address")
+	ErrInvalidIP        = errors.New("invalid IP address")
+	ErrInvalidBatch     = errors.New("invalid batch request")
+	ErrBatchTooLarge    = errors.New("batch size exceeds the limit")
+	ErrDNSNameOutOfZone = errors.New("DNS name is out of zone")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrNotFound         = errors.New("not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrRateLimited      = errors.New("rate limit exceeded")
+	ErrInvalidOrigin    = errors.New("invalid CORS origin")
+	ErrProfileNotFound  = errors.New("profile not found")
+	ErrInvalidRegexp    = errors.New("invalid regular expression")
 )
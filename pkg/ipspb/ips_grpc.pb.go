@@ -0,0 +1,278 @@
+//
+// Copyright (c) 2023 shenjunzheng@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: ips.proto
+
+package ipspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	IPS_Lookup_FullMethodName      = "/ips.v1.IPS/Lookup"
+	IPS_BatchLookup_FullMethodName = "/ips.v1.IPS/BatchLookup"
+	IPS_ParseText_FullMethodName   = "/ips.v1.IPS/ParseText"
+	IPS_Meta_FullMethodName        = "/ips.v1.IPS/Meta"
+)
+
+// IPSClient is the client API for IPS service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IPSClient interface {
+	// Lookup retrieves the information of an IP address.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*IPInfo, error)
+	// BatchLookup retrieves the information of a stream of IP addresses.
+	// A response is sent for each request, in order.
+	BatchLookup(ctx context.Context, opts ...grpc.CallOption) (IPS_BatchLookupClient, error)
+	// ParseText finds the IP addresses and domains in a text and retrieves their information.
+	ParseText(ctx context.Context, in *ParseTextRequest, opts ...grpc.CallOption) (*ParseTextResponse, error)
+	// Meta returns the meta-information of the loaded IPv4 and IPv6 databases.
+	Meta(ctx context.Context, in *MetaRequest, opts ...grpc.CallOption) (*MetaResponse, error)
+}
+
+type iPSClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIPSClient(cc grpc.ClientConnInterface) IPSClient {
+	return &iPSClient{cc}
+}
+
+func (c *iPSClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*IPInfo, error) {
+	out := new(IPInfo)
+	err := c.cc.Invoke(ctx, IPS_Lookup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPSClient) BatchLookup(ctx context.Context, opts ...grpc.CallOption) (IPS_BatchLookupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IPS_ServiceDesc.Streams[0], IPS_BatchLookup_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &iPSBatchLookupClient{stream}
+	return x, nil
+}
+
+type IPS_BatchLookupClient interface {
+	Send(*LookupRequest) error
+	Recv() (*BatchLookupResponse, error)
+	grpc.ClientStream
+}
+
+type iPSBatchLookupClient struct {
+	grpc.ClientStream
+}
+
+func (x *iPSBatchLookupClient) Send(m *LookupRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *iPSBatchLookupClient) Recv() (*BatchLookupResponse, error) {
+	m := new(BatchLookupResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *iPSClient) ParseText(ctx context.Context, in *ParseTextRequest, opts ...grpc.CallOption) (*ParseTextResponse, error) {
+	out := new(ParseTextResponse)
+	err := c.cc.Invoke(ctx, IPS_ParseText_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPSClient) Meta(ctx context.Context, in *MetaRequest, opts ...grpc.CallOption) (*MetaResponse, error) {
+	out := new(MetaResponse)
+	err := c.cc.Invoke(ctx, IPS_Meta_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IPSServer is the server API for IPS service.
+// All implementations must embed UnimplementedIPSServer
+// for forward compatibility
+type IPSServer interface {
+	// Lookup retrieves the information of an IP address.
+	Lookup(context.Context, *LookupRequest) (*IPInfo, error)
+	// BatchLookup retrieves the information of a stream of IP addresses.
+	// A response is sent for each request, in order.
+	BatchLookup(IPS_BatchLookupServer) error
+	// ParseText finds the IP addresses and domains in a text and retrieves their information.
+	ParseText(context.Context, *ParseTextRequest) (*ParseTextResponse, error)
+	// Meta returns the meta-information of the loaded IPv4 and IPv6 databases.
+	Meta(context.Context, *MetaRequest) (*MetaResponse, error)
+	mustEmbedUnimplementedIPSServer()
+}
+
+// UnimplementedIPSServer must be embedded to have forward compatible implementations.
+type UnimplementedIPSServer struct {
+}
+
+func (UnimplementedIPSServer) Lookup(context.Context, *LookupRequest) (*IPInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedIPSServer) BatchLookup(IPS_BatchLookupServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchLookup not implemented")
+}
+func (UnimplementedIPSServer) ParseText(context.Context, *ParseTextRequest) (*ParseTextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ParseText not implemented")
+}
+func (UnimplementedIPSServer) Meta(context.Context, *MetaRequest) (*MetaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Meta not implemented")
+}
+func (UnimplementedIPSServer) mustEmbedUnimplementedIPSServer() {}
+
+// UnsafeIPSServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IPSServer will
+// result in compilation errors.
+type UnsafeIPSServer interface {
+	mustEmbedUnimplementedIPSServer()
+}
+
+func RegisterIPSServer(s grpc.ServiceRegistrar, srv IPSServer) {
+	s.RegisterService(&IPS_ServiceDesc, srv)
+}
+
+func _IPS_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPSServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPS_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPSServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPS_BatchLookup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IPSServer).BatchLookup(&iPSBatchLookupServer{stream})
+}
+
+type IPS_BatchLookupServer interface {
+	Send(*BatchLookupResponse) error
+	Recv() (*LookupRequest, error)
+	grpc.ServerStream
+}
+
+type iPSBatchLookupServer struct {
+	grpc.ServerStream
+}
+
+func (x *iPSBatchLookupServer) Send(m *BatchLookupResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *iPSBatchLookupServer) Recv() (*LookupRequest, error) {
+	m := new(LookupRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _IPS_ParseText_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseTextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPSServer).ParseText(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPS_ParseText_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPSServer).ParseText(ctx, req.(*ParseTextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPS_Meta_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPSServer).Meta(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPS_Meta_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPSServer).Meta(ctx, req.(*MetaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IPS_ServiceDesc is the grpc.ServiceDesc for IPS service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IPS_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ips.v1.IPS",
+	HandlerType: (*IPSServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _IPS_Lookup_Handler,
+		},
+		{
+			MethodName: "ParseText",
+			Handler:    _IPS_ParseText_Handler,
+		},
+		{
+			MethodName: "Meta",
+			Handler:    _IPS_Meta_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchLookup",
+			Handler:       _IPS_BatchLookup_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ips.proto",
+}
@@ -24,13 +24,13 @@ import (
 type DomainInfo struct {
 
 	// Domain is the domain name.
-	Domain string `json:"domain"`
+	Domain string `json:"domain" yaml:"domain"`
 
 	// MainDomain is the main domain name.
-	MainDomain string `json:"main_domain"`
+	MainDomain string `json:"main_domain" yaml:"main_domain"`
 
 	// Data holds the actual information related to the domain.
-	Data map[string]string `json:"data"`
+	Data map[string]string `json:"data" yaml:"data"`
 }
 
 // Values extracts the values from the Data map, sorts them alphabetically, and returns them as a slice of strings.
@@ -91,31 +91,34 @@ func (i *IPInfo) Values() []string {
 
 // IPInfoOutput represents the structure for outputting IP information.
 type IPInfoOutput struct {
-	IP   string            `json:"ip"`
-	Net  string            `json:"net"`
-	Data map[string]string `json:"data"`
+	IP   string            `json:"ip" yaml:"ip"`
+	Net  string            `json:"net" yaml:"net"`
+	Data map[string]string `json:"data" yaml:"data"`
 }
 
-// Output constructs and returns an IPInfoOutput based on the current IPInfo.
+// OutputFields returns the names of the fields in the output, in order.
 // It decides whether to use the database field or the common field based on the dbFiled flag.
-func (i *IPInfo) Output(dbFiled bool) *IPInfoOutput {
-	data := make(map[string]string, len(i.Fields))
-	values := i.Values()
-
+func (i *IPInfo) OutputFields(dbFiled bool) []string {
+	ret := make([]string, len(i.Fields))
 	fieldAliasReverse := make(map[string]string, len(i.FieldAlias))
 	for commonField, dbField := range i.FieldAlias {
 		fieldAliasReverse[dbField] = commonField
 	}
 	for index, field := range i.Fields {
-		if dbFiled {
-			data[field] = values[index]
-			continue
+		ret[index] = field
+		if commonField, ok := fieldAliasReverse[field]; ok && !dbFiled {
+			ret[index] = commonField
 		}
+	}
+	return ret
+}
 
-		if commonField, ok := fieldAliasReverse[field]; ok {
-			data[commonField] = values[index]
-			continue
-		}
+// Output constructs and returns an IPInfoOutput based on the current IPInfo.
+// It decides whether to use the database field or the common field based on the dbFiled flag.
+func (i *IPInfo) Output(dbFiled bool) *IPInfoOutput {
+	data := make(map[string]string, len(i.Fields))
+	values := i.Values()
+	for index, field := range i.OutputFields(dbFiled) {
 		data[field] = values[index]
 	}
 
@@ -46,8 +46,8 @@ type AlfredText struct {
 
 // DataList holds a list of items to be displayed in Alfred's result list.
 type DataList struct {
-	Items   []interface{} `json:"items,omitempty"`
-	Domains []interface{} `json:"domains,omitempty"`
+	Items   []interface{} `json:"items,omitempty" yaml:"items,omitempty"`
+	Domains []interface{} `json:"domains,omitempty" yaml:"domains,omitempty"`
 }
 
 // AddItem appends a new item to the DataList's Items slice.